@@ -0,0 +1,152 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+)
+
+// CodeOwners maps file paths to their owners as defined by a CODEOWNERS file.
+type CodeOwners struct {
+	rules []codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern PathPattern
+	owners  []string
+}
+
+// ParseCodeOwners parses the content of a CODEOWNERS file. Each line is a
+// path pattern with gitignore-like syntax followed by any number of owners,
+// which are users ("@login"), teams ("@org/team-slug"), or email addresses.
+func ParseCodeOwners(content []byte) *CodeOwners {
+	var co CodeOwners
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		co.rules = append(co.rules, codeOwnersRule{
+			pattern: NewPathPattern(fields[0]),
+			owners:  fields[1:],
+		})
+	}
+
+	return &co
+}
+
+// Owners returns the owners of a path. The last matching rule wins, so a
+// later rule without owners removes ownership.
+func (co *CodeOwners) Owners(path string) []string {
+	for i := len(co.rules) - 1; i >= 0; i-- {
+		if co.rules[i].pattern.Matches(path) {
+			return co.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// missingCodeOwners returns the owners of changed files that have no
+// approval from any of their owners. Each element lists the alternative
+// owners of a file, separated by spaces. Owners given as email addresses
+// cannot be matched to reviewers and are ignored.
+func missingCodeOwners(ctx context.Context, pullCtx pull.Context, approvedBy []string) ([]string, error) {
+	content, err := pullCtx.CodeOwners(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch CODEOWNERS")
+	}
+	if content == nil {
+		return nil, nil
+	}
+	codeOwners := ParseCodeOwners(content)
+
+	files, err := pullCtx.ChangedFiles(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list changed files")
+	}
+
+	approved := make(map[string]bool)
+	for _, login := range approvedBy {
+		approved[strings.ToLower(login)] = true
+	}
+
+	missing := make(map[string]bool)
+	for _, file := range files {
+		var owners []string
+		satisfied := false
+
+		for _, owner := range codeOwners.Owners(file) {
+			if !strings.HasPrefix(owner, "@") {
+				continue
+			}
+			owners = append(owners, owner)
+
+			ok, err := isApprovedBy(ctx, pullCtx, strings.TrimPrefix(owner, "@"), approved)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				satisfied = true
+				break
+			}
+		}
+
+		if !satisfied && len(owners) > 0 {
+			missing[strings.Join(owners, " ")] = true
+		}
+	}
+
+	var result []string
+	for owners := range missing {
+		result = append(result, owners)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// isApprovedBy returns true if the owner, a login or an "<org>/<team-slug>",
+// is or has a member among the approving reviewers.
+func isApprovedBy(ctx context.Context, pullCtx pull.Context, owner string, approved map[string]bool) (bool, error) {
+	if !strings.Contains(owner, "/") {
+		return approved[strings.ToLower(owner)], nil
+	}
+
+	members, err := pullCtx.TeamMembers(ctx, owner)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list members of code owner %s", owner)
+	}
+	for _, member := range members {
+		if approved[strings.ToLower(member)] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
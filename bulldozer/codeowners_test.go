@@ -0,0 +1,90 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
+)
+
+const testCodeOwners = `
+# default owners
+*       @global-owner
+
+*.js    @js-owner # inline comment
+/build/output/ @doctocat
+docs/*  docs@example.com
+apps/   @octocat
+**/logs @logs-owner
+/vendor/
+`
+
+func TestCodeOwners(t *testing.T) {
+	co := ParseCodeOwners([]byte(testCodeOwners))
+
+	tests := map[string][]string{
+		"README.md":               {"@global-owner"},
+		"src/app.js":              {"@js-owner"},
+		"build/output/out.txt":    {"@doctocat"},
+		"docs/index.md":           {"docs@example.com"},
+		"docs/nested/index.md":    {"@global-owner"},
+		"apps/web/main.go":        {"@octocat"},
+		"lib/apps/main.go":        {"@octocat"},
+		"deep/path/logs/file.txt": {"@logs-owner"},
+		"vendor/lib/lib.go":       {},
+	}
+
+	for path, expected := range tests {
+		assert.Equal(t, expected, co.Owners(path), path)
+	}
+}
+
+func TestMissingCodeOwners(t *testing.T) {
+	ctx := context.Background()
+
+	pc := &pulltest.MockPullContext{
+		HeadSHAValue: "head",
+		CodeOwnersValue: []byte(`
+*.go    @org/backend
+*.md    @alice @bob
+/vendor/
+`),
+		ChangedFilesValue: []string{"main.go", "README.md", "vendor/lib.go"},
+		TeamMembersValue: map[string][]string{
+			"org/backend": {"Carol"},
+		},
+	}
+
+	missing, err := missingCodeOwners(ctx, pc, []string{"bob"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"@org/backend"}, missing)
+
+	missing, err = missingCodeOwners(ctx, pc, []string{"bob", "carol"})
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	pc.ReviewsValue = []pull.Review{
+		{Author: "alice", State: pull.ReviewApproved, CommitID: "head"},
+	}
+	state, err := evaluateReviews(ctx, pc, ReviewConfig{RequireCodeOwners: true})
+	require.NoError(t, err)
+	assert.Equal(t, "missing approval from code owners @org/backend", state.unsatisfied)
+}
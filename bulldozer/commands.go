@@ -0,0 +1,203 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+)
+
+type Command string
+
+const (
+	// CommandMerge whitelists the pull request
+	CommandMerge Command = "merge"
+	// CommandHold blacklists the pull request
+	CommandHold Command = "hold"
+	// CommandCancel retracts earlier commands
+	CommandCancel Command = "cancel"
+)
+
+// CommandMatch is the latest command found in the comments of a pull
+// request.
+type CommandMatch struct {
+	Command Command `json:"command"`
+	// Text is the command text that matched
+	Text      string    `json:"text"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+	// Method overrides the configured merge method, if set
+	Method MergeMethod `json:"method,omitempty"`
+}
+
+// SlashCommandPrefix starts the slash commands that bulldozer understands
+// without configuration, like "/bulldozer merge squash".
+const SlashCommandPrefix = "/bulldozer"
+
+// Names of slash commands
+const (
+	SlashMerge   = "merge"
+	SlashUpdate  = "update"
+	SlashStatus  = "status"
+	SlashQueue   = "queue"
+	SlashCancel  = "cancel"
+	SlashExplain = "explain"
+)
+
+// SlashCommand is a parsed slash command.
+type SlashCommand struct {
+	Name string
+	Args []string
+}
+
+func (c SlashCommand) String() string {
+	return strings.Join(append([]string{SlashCommandPrefix, c.Name}, c.Args...), " ")
+}
+
+// ParseSlashCommand returns the slash command of a comment, or false if the
+// comment is not a slash command. A comment is a slash command if its first
+// line starts with SlashCommandPrefix. The command is not validated.
+func ParseSlashCommand(body string) (SlashCommand, bool) {
+	line := strings.TrimSpace(body)
+	if idx := strings.IndexAny(line, "\r\n"); idx >= 0 {
+		line = line[:idx]
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], SlashCommandPrefix) {
+		return SlashCommand{}, false
+	}
+
+	var command SlashCommand
+	if len(fields) > 1 {
+		command.Name = strings.ToLower(fields[1])
+		command.Args = fields[2:]
+	}
+	return command, true
+}
+
+// Validate returns an error describing the problem if the command is not
+// known or has invalid arguments.
+func (c SlashCommand) Validate() error {
+	switch c.Name {
+	case SlashMerge:
+		if len(c.Args) > 1 {
+			return errors.Errorf("%q takes at most one merge method", c.Name)
+		}
+		if len(c.Args) == 1 && !isValidMergeMethod(MergeMethod(strings.ToLower(c.Args[0]))) {
+			return errors.Errorf("unknown merge method %q, expected one of %s, %s, or %s", c.Args[0], SquashAndMerge, RebaseAndMerge, MergeCommit)
+		}
+	case SlashUpdate, SlashStatus, SlashQueue, SlashCancel, SlashExplain:
+		if len(c.Args) > 0 {
+			return errors.Errorf("%q takes no arguments", c.Name)
+		}
+	case "":
+		return errors.New("missing command")
+	default:
+		return errors.Errorf("unknown command %q", c.Name)
+	}
+	return nil
+}
+
+// Method returns the merge method argument of a merge command, if any.
+func (c SlashCommand) Method() MergeMethod {
+	if c.Name != SlashMerge || len(c.Args) == 0 {
+		return ""
+	}
+	return MergeMethod(strings.ToLower(c.Args[0]))
+}
+
+// slashCommandTrust is the trust required for slash commands
+var slashCommandTrust = TrustedCommenters{WritePermission: true}
+
+// parseCommand returns the command of a comment, or false if the comment
+// is not a command. A comment is a command if its first line is one of the
+// configured command texts, ignoring case and surrounding space.
+func parseCommand(config CommandConfig, body string) (Command, string, bool) {
+	line := strings.TrimSpace(body)
+	if idx := strings.IndexAny(line, "\r\n"); idx >= 0 {
+		line = strings.TrimSpace(line[:idx])
+	}
+
+	for _, c := range []struct {
+		command Command
+		texts   []string
+	}{
+		{CommandMerge, config.Merge},
+		{CommandHold, config.Hold},
+		{CommandCancel, config.Cancel},
+	} {
+		for _, text := range c.texts {
+			if strings.EqualFold(line, strings.TrimSpace(text)) {
+				return c.command, text, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// LatestCommand processes the comments of a pull request in the order they
+// were created and returns the latest command of a trusted commenter, or nil
+// if there is none or the latest command is CommandCancel. If slash commands
+// are enabled, the merge and cancel slash commands of users with write
+// permission count besides the configured commands, which take precedence.
+func LatestCommand(ctx context.Context, pullCtx pull.Context, config CommandConfig) (*CommandMatch, error) {
+	comments, err := pullCtx.Comments(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list comments")
+	}
+
+	trust := newCommenterTrust(pullCtx, config.TrustedCommenters)
+	slashTrust := newCommenterTrust(pullCtx, slashCommandTrust)
+
+	var latest *CommandMatch
+	for _, comment := range comments {
+		match := &CommandMatch{Author: comment.Author, CreatedAt: comment.CreatedAt}
+		commentTrust := trust
+
+		if command, text, ok := parseCommand(config, comment.Body); ok {
+			match.Command = command
+			match.Text = text
+		} else if slash, ok := ParseSlashCommand(comment.Body); ok && config.SlashCommands {
+			if slash.Validate() != nil || (slash.Name != SlashMerge && slash.Name != SlashCancel) {
+				continue
+			}
+			match.Command = Command(slash.Name)
+			match.Text = slash.String()
+			match.Method = slash.Method()
+			commentTrust = slashTrust
+		} else {
+			continue
+		}
+
+		trusted, err := commentTrust.trusts(ctx, comment.Author)
+		if err != nil {
+			return nil, err
+		}
+		if trusted {
+			latest = match
+		}
+	}
+
+	if latest != nil && latest.Command == CommandCancel {
+		return nil, nil
+	}
+	return latest, nil
+}
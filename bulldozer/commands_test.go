@@ -0,0 +1,268 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
+)
+
+func TestLatestCommand(t *testing.T) {
+	config := CommandConfig{
+		Merge:  []string{"/bulldozer merge"},
+		Hold:   []string{"/bulldozer hold"},
+		Cancel: []string{"/bulldozer cancel"},
+	}
+
+	ctx := context.Background()
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	comment := func(minute int, author, body string) pull.Comment {
+		return pull.Comment{Body: body, Author: author, CreatedAt: start.Add(time.Duration(minute) * time.Minute)}
+	}
+
+	t.Run("latestCommandWins", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			CommentsValue: []pull.Comment{
+				comment(0, "alice", "/bulldozer hold"),
+				comment(1, "bob", "looks good"),
+				comment(2, "alice", "  /BULLDOZER MERGE  \nthanks!"),
+			},
+		}
+
+		command, err := LatestCommand(ctx, pc, config)
+		require.NoError(t, err)
+		require.NotNil(t, command)
+		assert.Equal(t, CommandMerge, command.Command)
+		assert.Equal(t, "alice", command.Author)
+		assert.Equal(t, start.Add(2*time.Minute), command.CreatedAt)
+	})
+
+	t.Run("cancelRetractsCommands", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			CommentsValue: []pull.Comment{
+				comment(0, "alice", "/bulldozer merge"),
+				comment(1, "alice", "/bulldozer cancel"),
+			},
+		}
+
+		command, err := LatestCommand(ctx, pc, config)
+		require.NoError(t, err)
+		assert.Nil(t, command)
+	})
+
+	t.Run("mentionIsNotACommand", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			CommentsValue: []pull.Comment{
+				comment(0, "alice", "please don't use /bulldozer merge yet"),
+			},
+		}
+
+		command, err := LatestCommand(ctx, pc, config)
+		require.NoError(t, err)
+		assert.Nil(t, command)
+	})
+
+	t.Run("untrustedCommandsAreIgnored", func(t *testing.T) {
+		trusted := config
+		trusted.TrustedCommenters = TrustedCommenters{Users: []string{"alice"}}
+
+		pc := &pulltest.MockPullContext{
+			CommentsValue: []pull.Comment{
+				comment(0, "alice", "/bulldozer hold"),
+				comment(1, "mallory", "/bulldozer merge"),
+			},
+		}
+
+		command, err := LatestCommand(ctx, pc, trusted)
+		require.NoError(t, err)
+		require.NotNil(t, command)
+		assert.Equal(t, CommandHold, command.Command)
+	})
+}
+
+func TestShouldMergeWithCommands(t *testing.T) {
+	mergeConfig := MergeConfig{
+		Blacklist: Signals{Labels: []string{"do not merge"}},
+		Commands: CommandConfig{
+			Merge: []string{"/bulldozer merge"},
+			Hold:  []string{"/bulldozer hold"},
+		},
+	}
+
+	ctx := context.Background()
+
+	t.Run("mergeCommandIsRequired", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{}
+
+		result, err := ShouldMergePR(ctx, pc, mergeConfig)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+	})
+
+	t.Run("mergeCommandAllowsMerge", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			CommentsValue: []pull.Comment{{Body: "/bulldozer hold"}, {Body: "/bulldozer merge"}},
+		}
+
+		result, err := ShouldMergePR(ctx, pc, mergeConfig)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("holdCommandPreventsMerge", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			CommentsValue: []pull.Comment{{Body: "/bulldozer merge"}, {Body: "/bulldozer hold", Author: "alice"}},
+		}
+
+		result, err := ShouldMergePR(ctx, pc, mergeConfig)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, "on hold by alice", result.Summary())
+	})
+
+	t.Run("mergeCommandDoesntSatisfyWhitelist", func(t *testing.T) {
+		whitelisted := mergeConfig
+		whitelisted.Whitelist = Signals{Labels: []string{"merge when ready"}}
+
+		pc := &pulltest.MockPullContext{
+			CommentsValue: []pull.Comment{{Body: "/bulldozer merge"}},
+		}
+
+		result, err := ShouldMergePR(ctx, pc, whitelisted)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, "whitelisting is enabled and no whitelist signal detected", result.Reason)
+	})
+
+	t.Run("mergeCommandOverridesWhitelist", func(t *testing.T) {
+		whitelisted := mergeConfig
+		whitelisted.Whitelist = Signals{Labels: []string{"merge when ready"}}
+		whitelisted.Commands.OverrideWhitelist = true
+
+		pc := &pulltest.MockPullContext{
+			CommentsValue: []pull.Comment{{Body: "/bulldozer merge"}},
+		}
+
+		result, err := ShouldMergePR(ctx, pc, whitelisted)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("holdOnlyCommandsDontRequireMergeCommand", func(t *testing.T) {
+		holdOnly := MergeConfig{
+			Commands: CommandConfig{
+				Hold:   []string{"/bulldozer hold"},
+				Cancel: []string{"/bulldozer cancel"},
+			},
+		}
+
+		pc := &pulltest.MockPullContext{}
+		result, err := ShouldMergePR(ctx, pc, holdOnly)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+
+		pc.CommentsValue = []pull.Comment{{Body: "/bulldozer hold"}}
+		result, err = ShouldMergePR(ctx, pc, holdOnly)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+
+		pc.CommentsValue = append(pc.CommentsValue, pull.Comment{Body: "/bulldozer cancel"})
+		result, err = ShouldMergePR(ctx, pc, holdOnly)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "cancel must retract the hold")
+	})
+
+	t.Run("blacklistOverridesMergeCommand", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue:    []string{"do not merge"},
+			CommentsValue: []pull.Comment{{Body: "/bulldozer merge"}},
+		}
+
+		result, err := ShouldMergePR(ctx, pc, mergeConfig)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.NotNil(t, result.Blacklist)
+	})
+}
+
+func TestParseSlashCommand(t *testing.T) {
+	command, ok := ParseSlashCommand("  /Bulldozer MERGE squash\nplease")
+	require.True(t, ok)
+	assert.Equal(t, SlashMerge, command.Name)
+	assert.Equal(t, []string{"squash"}, command.Args)
+	assert.NoError(t, command.Validate())
+	assert.Equal(t, SquashAndMerge, command.Method())
+	assert.Equal(t, "/bulldozer merge squash", command.String())
+
+	_, ok = ParseSlashCommand("please run /bulldozer merge")
+	assert.False(t, ok)
+
+	_, ok = ParseSlashCommand("/bulldozers merge")
+	assert.False(t, ok)
+
+	for body, valid := range map[string]bool{
+		"/bulldozer merge":        true,
+		"/bulldozer merge rebase": true,
+		"/bulldozer merge fast":   false,
+		"/bulldozer update":       true,
+		"/bulldozer status":       true,
+		"/bulldozer queue":        true,
+		"/bulldozer cancel":       true,
+		"/bulldozer cancel now":   false,
+		"/bulldozer explain":      true,
+		"/bulldozer deploy":       false,
+		"/bulldozer":              false,
+	} {
+		command, ok := ParseSlashCommand(body)
+		require.True(t, ok, body)
+		assert.Equal(t, valid, command.Validate() == nil, body)
+	}
+}
+
+func TestLatestSlashCommand(t *testing.T) {
+	ctx := context.Background()
+	pc := &pulltest.MockPullContext{
+		CommentsValue: []pull.Comment{
+			{Body: "/bulldozer merge rebase", Author: "writer"},
+			{Body: "/bulldozer cancel", Author: "reader"},
+			{Body: "/bulldozer status", Author: "writer"},
+		},
+		PermissionValue: map[string]string{"writer": "write", "reader": "read"},
+	}
+
+	command, err := LatestCommand(ctx, pc, CommandConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, command, "slash commands are disabled by default")
+
+	config := CommandConfig{SlashCommands: true}
+	command, err = LatestCommand(ctx, pc, config)
+	require.NoError(t, err)
+	require.NotNil(t, command)
+	assert.Equal(t, CommandMerge, command.Command)
+	assert.Equal(t, RebaseAndMerge, command.Method)
+	assert.Equal(t, "writer", command.Author)
+
+	pc.PermissionValue["reader"] = "admin"
+	command, err = LatestCommand(ctx, pc, config)
+	require.NoError(t, err)
+	assert.Nil(t, command)
+}
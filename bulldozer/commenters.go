@@ -0,0 +1,121 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+)
+
+// commenterTrust decides if users are trusted commenters, looking up each
+// user at most once.
+type commenterTrust struct {
+	pullCtx pull.Context
+	config  TrustedCommenters
+	trusted map[string]bool
+}
+
+func newCommenterTrust(pullCtx pull.Context, config TrustedCommenters) *commenterTrust {
+	return &commenterTrust{
+		pullCtx: pullCtx,
+		config:  config,
+		trusted: make(map[string]bool),
+	}
+}
+
+func (c *commenterTrust) trusts(ctx context.Context, user string) (bool, error) {
+	if !c.config.Enabled() {
+		return true, nil
+	}
+	if user == "" {
+		return false, nil
+	}
+
+	key := strings.ToLower(user)
+	if trusted, ok := c.trusted[key]; ok {
+		return trusted, nil
+	}
+
+	trusted, err := c.lookup(ctx, user)
+	if err != nil {
+		return false, err
+	}
+	c.trusted[key] = trusted
+	return trusted, nil
+}
+
+func (c *commenterTrust) lookup(ctx context.Context, user string) (bool, error) {
+	for _, u := range c.config.Users {
+		if strings.EqualFold(u, user) {
+			return true, nil
+		}
+	}
+
+	if c.config.WritePermission {
+		permission, err := c.pullCtx.Permission(ctx, user)
+		if err != nil && !isNotFound(errors.Cause(err)) {
+			return false, errors.Wrapf(err, "failed to get permission of commenter %s", user)
+		}
+		// GitHub returns 404 for users that are not collaborators, like
+		// bots and former members, which have no permission
+		if permission == "admin" || permission == "write" {
+			return true, nil
+		}
+	}
+
+	for _, team := range c.config.Teams {
+		members, err := c.pullCtx.TeamMembers(ctx, team)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to list members of trusted team %s", team)
+		}
+		for _, member := range members {
+			if strings.EqualFold(member, user) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// trustedComments returns the comments written by trusted commenters.
+func (c *commenterTrust) trustedComments(ctx context.Context, comments []pull.Comment) ([]pull.Comment, error) {
+	if !c.config.Enabled() {
+		return comments, nil
+	}
+
+	var trusted []pull.Comment
+	for _, comment := range comments {
+		ok, err := c.trusts(ctx, comment.Author)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			trusted = append(trusted, comment)
+		}
+	}
+	return trusted, nil
+}
+
+func isNotFound(err error) bool {
+	rerr, ok := err.(*github.ErrorResponse)
+	return ok && rerr.Response != nil && rerr.Response.StatusCode == http.StatusNotFound
+}
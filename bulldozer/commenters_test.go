@@ -0,0 +1,132 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
+)
+
+func TestTrustedCommenters(t *testing.T) {
+	whitelist := Signals{
+		Comments:          []string{"merge please"},
+		CommentSubstrings: []string{"==MERGE_WHEN_READY=="},
+		TrustedCommenters: TrustedCommenters{
+			WritePermission: true,
+			Users:           []string{"release-bot"},
+			Teams:           []string{"org/maintainers"},
+		},
+	}
+
+	ctx := context.Background()
+
+	newContext := func(comments ...pull.Comment) *pulltest.MockPullContext {
+		return &pulltest.MockPullContext{
+			AuthorValue:      "outsider",
+			CommentsValue:    comments,
+			PermissionValue:  map[string]string{"writer": "write", "reader": "read"},
+			TeamMembersValue: map[string][]string{"org/maintainers": {"Maintainer"}},
+		}
+	}
+
+	t.Run("untrustedCommentIsIgnored", func(t *testing.T) {
+		pc := newContext(pull.Comment{Body: "merge please", Author: "reader"})
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, whitelist)
+		require.Nil(t, err)
+		assert.False(t, actualWhitelist)
+	})
+
+	t.Run("writePermissionIsTrusted", func(t *testing.T) {
+		pc := newContext(pull.Comment{Body: "merge please", Author: "writer"})
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+	})
+
+	t.Run("userIsTrusted", func(t *testing.T) {
+		pc := newContext(pull.Comment{Body: "ok ==MERGE_WHEN_READY==", Author: "Release-Bot"})
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+	})
+
+	t.Run("teamMemberIsTrusted", func(t *testing.T) {
+		pc := newContext(pull.Comment{Body: "merge please", Author: "maintainer"})
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+	})
+
+	t.Run("untrustedBodyIsIgnored", func(t *testing.T) {
+		pc := newContext()
+		pc.BodyValue = "==MERGE_WHEN_READY=="
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, whitelist)
+		require.Nil(t, err)
+		assert.False(t, actualWhitelist)
+
+		pc.AuthorValue = "writer"
+		actualWhitelist, _, err = IsPRWhitelisted(ctx, pc, whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+	})
+
+	t.Run("errPermissionFailsClosedWhitelist", func(t *testing.T) {
+		pc := newContext(pull.Comment{Body: "merge please", Author: "writer"})
+		pc.PermissionErrValue = errors.New("failure")
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, whitelist)
+		require.NotNil(t, err)
+		assert.False(t, actualWhitelist)
+	})
+
+	t.Run("unknownPermissionIsUntrusted", func(t *testing.T) {
+		pc := newContext(pull.Comment{Body: "merge please", Author: "codecov[bot]"})
+		pc.PermissionErrValue = errors.Wrap(&github.ErrorResponse{
+			Response: &http.Response{StatusCode: http.StatusNotFound},
+			Message:  "Not Found",
+		}, "failed to get permission")
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, whitelist)
+		require.Nil(t, err)
+		assert.False(t, actualWhitelist)
+
+		pc.CommentsValue = append(pc.CommentsValue, pull.Comment{Body: "merge please", Author: "maintainer"})
+		actualWhitelist, _, err = IsPRWhitelisted(ctx, pc, whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+	})
+
+	t.Run("allCommentersTrustedByDefault", func(t *testing.T) {
+		pc := newContext(pull.Comment{Body: "merge please", Author: "reader"})
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, Signals{Comments: []string{"merge please"}})
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultStatusContext = "bulldozer"
+
+	// maxStatusDescription is the longest description GitHub accepts for a
+	// commit status
+	maxStatusDescription = 140
+)
+
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	StatusError   = "error"
+)
+
+// StatusReporter publishes why bulldozer is or is not merging a pull request
+// as a commit status on its head commit.
+type StatusReporter struct {
+	// Context is the name of the commit status
+	Context string
+	// Disabled turns reporting into a no-op
+	Disabled bool
+}
+
+// Report sets the bulldozer commit status of a commit. The status is only
+// written if its state or description changed.
+func (r StatusReporter) Report(ctx context.Context, client *github.Client, owner, repo, sha, state, description string) error {
+	if r.Disabled || sha == "" {
+		return nil
+	}
+
+	statusContext := r.Context
+	if statusContext == "" {
+		statusContext = DefaultStatusContext
+	}
+	description = truncateDescription(description)
+
+	combined, _, err := client.Repositories.GetCombinedStatus(ctx, owner, repo, sha, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get statuses of %s", sha)
+	}
+	for _, s := range combined.Statuses {
+		if s.GetContext() == statusContext && s.GetState() == state && s.GetDescription() == description {
+			return nil
+		}
+	}
+
+	status := &github.RepoStatus{
+		Context:     github.String(statusContext),
+		State:       github.String(state),
+		Description: github.String(description),
+	}
+	if _, _, err := client.Repositories.CreateStatus(ctx, owner, repo, sha, status); err != nil {
+		return errors.Wrapf(err, "failed to set %s status of %s", statusContext, sha)
+	}
+	return nil
+}
+
+// IsOwnStatus returns true if a status with the given context was posted by
+// this reporter.
+func (r StatusReporter) IsOwnStatus(statusContext string) bool {
+	if r.Context == "" {
+		return statusContext == DefaultStatusContext
+	}
+	return statusContext == r.Context
+}
+
+func truncateDescription(description string) string {
+	runes := []rune(description)
+	if len(runes) <= maxStatusDescription {
+		return description
+	}
+	return string(runes[:maxStatusDescription-3]) + "..."
+}
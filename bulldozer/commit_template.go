@@ -0,0 +1,241 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+)
+
+// CommitMessageData is the data available to commit title and message
+// templates.
+type CommitMessageData struct {
+	Title  string
+	Number int
+	Body   string
+	// DelimitedBody is the part of the body between two message delimiters,
+	// or the whole body if there is no delimited part
+	DelimitedBody string
+
+	Author    string
+	Commits   []CommitData
+	Labels    []string
+	Reviewers []string
+	// Approvers are the reviewers whose latest review is an approval
+	Approvers []string
+	// ReviewerIDs maps the logins of Reviewers to their GitHub user IDs
+	ReviewerIDs map[string]int64
+	// LinkedIssues are the issues the body closes, e.g. "#12" or
+	// "owner/repo#12"
+	LinkedIssues []string
+}
+
+// CommitData describes a commit of a pull request.
+type CommitData struct {
+	SHA string
+	// Title is the first line of the message
+	Title       string
+	Message     string
+	Author      string
+	AuthorName  string
+	AuthorEmail string
+
+	Committer      string
+	CommitterName  string
+	CommitterEmail string
+}
+
+var templateFuncs = template.FuncMap{
+	"join":      strings.Join,
+	"trim":      strings.TrimSpace,
+	"firstLine": firstLine,
+}
+
+func parseCommitTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// renderCommitTemplate executes a commit title or message template.
+func renderCommitTemplate(name, text string, data CommitMessageData) (string, error) {
+	tmpl, err := parseCommitTemplate(name, text)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse %s template", name)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", errors.Wrapf(err, "failed to execute %s template", name)
+	}
+	return sb.String(), nil
+}
+
+// renderCommitTitle executes a title template. Titles are a single line, so
+// only the first line of the result is used.
+func renderCommitTitle(text string, data CommitMessageData) (string, error) {
+	title, err := renderCommitTemplate("title", text, data)
+	if err != nil {
+		return "", err
+	}
+	return firstLine(strings.TrimSpace(title)), nil
+}
+
+func firstLine(s string) string {
+	if idx := strings.IndexAny(s, "\r\n"); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
+
+// delimitedBody returns the part of body between two delimiters, or body if
+// delimiter is empty or does not appear twice.
+func delimitedBody(body, delimiter string) (string, error) {
+	if delimiter == "" {
+		return body, nil
+	}
+
+	var quotedDelimiter = regexp.QuoteMeta(delimiter)
+	var rString = fmt.Sprintf(`(?sm:(%s\s*)^(.*)$(\s*%s))`, quotedDelimiter, quotedDelimiter)
+	matcher, err := regexp.Compile(rString)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to compile message delimiter regex")
+	}
+
+	if m := matcher.FindStringSubmatch(body); len(m) == 4 {
+		return m[2], nil
+	}
+	return body, nil
+}
+
+var linkedIssuePattern = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+((?:[\w.-]+/[\w.-]+)?#\d+)\b`)
+
+// linkedIssues returns the issues a pull request body closes using GitHub's
+// closing keywords, in order of appearance and without duplicates.
+func linkedIssues(body string) []string {
+	var issues []string
+	seen := make(map[string]bool)
+	for _, m := range linkedIssuePattern.FindAllStringSubmatch(body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			issues = append(issues, m[1])
+		}
+	}
+	return issues
+}
+
+// newCommitMessageData collects the data for commit templates.
+func newCommitMessageData(ctx context.Context, pullCtx pull.Context, client *github.Client, option MergeOption) (CommitMessageData, error) {
+	data := CommitMessageData{Number: pullCtx.Number()}
+
+	var err error
+	if data.Title, err = pullCtx.Title(ctx); err != nil {
+		return data, errors.Wrap(err, "failed to determine pull request title")
+	}
+	if data.Body, err = pullCtx.Body(ctx); err != nil {
+		return data, errors.Wrap(err, "failed to determine pull request body")
+	}
+	if data.DelimitedBody, err = delimitedBody(data.Body, option.MessageDelimiter); err != nil {
+		return data, err
+	}
+	if data.Author, err = pullCtx.Author(ctx); err != nil {
+		return data, errors.Wrap(err, "failed to determine pull request author")
+	}
+	if data.Labels, err = pullCtx.Labels(ctx); err != nil {
+		return data, errors.Wrap(err, "failed to list pull request labels")
+	}
+	data.LinkedIssues = linkedIssues(data.Body)
+
+	reviews, err := pullCtx.Reviews(ctx)
+	if err != nil {
+		return data, errors.Wrap(err, "failed to list reviews")
+	}
+	data.Reviewers, data.Approvers = reviewers(reviews)
+	data.ReviewerIDs = make(map[string]int64)
+	for _, r := range reviews {
+		if r.AuthorID != 0 {
+			data.ReviewerIDs[r.Author] = r.AuthorID
+		}
+	}
+
+	if client != nil {
+		commits, err := allCommits(ctx, pullCtx, client)
+		if err != nil {
+			return data, errors.Wrapf(err, "cannot list commits for %q", pullCtx.Locator())
+		}
+		for _, c := range commits {
+			data.Commits = append(data.Commits, CommitData{
+				SHA:         c.GetSHA(),
+				Title:       firstLine(c.GetCommit().GetMessage()),
+				Message:     c.GetCommit().GetMessage(),
+				Author:      c.GetAuthor().GetLogin(),
+				AuthorName:  c.GetCommit().GetAuthor().GetName(),
+				AuthorEmail: c.GetCommit().GetAuthor().GetEmail(),
+
+				Committer:      c.GetCommitter().GetLogin(),
+				CommitterName:  c.GetCommit().GetCommitter().GetName(),
+				CommitterEmail: c.GetCommit().GetCommitter().GetEmail(),
+			})
+		}
+	}
+
+	return data, nil
+}
+
+// reviewers returns the users who reviewed, in order of their first review,
+// and the users whose latest approving or rejecting review is an approval.
+func reviewers(reviews []pull.Review) (all []string, approvers []string) {
+	latest := make(map[string]string)
+	for _, r := range reviews {
+		if _, ok := latest[r.Author]; !ok {
+			all = append(all, r.Author)
+			latest[r.Author] = ""
+		}
+		if r.State != pull.ReviewCommented {
+			latest[r.Author] = r.State
+		}
+	}
+
+	for _, author := range all {
+		if latest[author] == pull.ReviewApproved {
+			approvers = append(approvers, author)
+		}
+	}
+	return all, approvers
+}
+
+// validate checks that the templates of a merge option parse.
+func (o MergeOption) validate() error {
+	if o.Body == TemplateBody && o.Template == "" {
+		return errors.Errorf("body %q requires a template", TemplateBody)
+	}
+	if o.Template != "" {
+		if _, err := parseCommitTemplate("message", o.Template); err != nil {
+			return errors.Wrap(err, "invalid message template")
+		}
+	}
+	if o.Title != "" {
+		if _, err := parseCommitTemplate("title", o.Title); err != nil {
+			return errors.Wrap(err, "invalid title template")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
+)
+
+func TestCalculateCommitMessageTemplate(t *testing.T) {
+	ctx := context.Background()
+	pc := &pulltest.MockPullContext{
+		NumberValue: 42,
+		TitleValue:  "Add retries",
+		AuthorValue: "alice",
+		BodyValue:   "Retries merges.\n\n==COMMIT_MSG==\nRetry merges with backoff.\n==COMMIT_MSG==\n\nFixes #12, closes owner/other#3 and fixes #12",
+		LabelValue:  []string{"enhancement"},
+		ReviewsValue: []pull.Review{
+			{Author: "bob", State: pull.ReviewChangesRequested},
+			{Author: "carol", State: pull.ReviewCommented},
+			{Author: "bob", State: pull.ReviewApproved},
+		},
+	}
+
+	option := MergeOption{
+		Body:             TemplateBody,
+		MessageDelimiter: "==COMMIT_MSG==",
+		Title:            "{{.Title}} (#{{.Number}})\nignored",
+		Template:         "{{.DelimitedBody}}\n\nIssues: {{join .LinkedIssues \", \"}}\nReviewers: {{join .Reviewers \", \"}}\nApproved-by: {{join .Approvers \", \"}}\nLabels: {{join .Labels \", \"}}\nAuthor: {{.Author}}",
+	}
+
+	title, message, err := calculateCommitMessage(ctx, pc, nil, option)
+	require.NoError(t, err)
+	assert.Equal(t, "Add retries (#42)", title)
+	assert.Equal(t, "Retry merges with backoff.\n\nIssues: #12, owner/other#3\nReviewers: bob, carol\nApproved-by: bob\nLabels: enhancement\nAuthor: alice", message)
+
+	t.Run("titleWithOtherBody", func(t *testing.T) {
+		title, message, err := calculateCommitMessage(ctx, pc, nil, MergeOption{Body: EmptyBody, Title: "{{.Title}}"})
+		require.NoError(t, err)
+		assert.Equal(t, "Add retries", title)
+		assert.Equal(t, "", message)
+	})
+
+	t.Run("noTitle", func(t *testing.T) {
+		title, message, err := calculateCommitMessage(ctx, pc, nil, MergeOption{Body: PullRequestBody, MessageDelimiter: "==COMMIT_MSG=="})
+		require.NoError(t, err)
+		assert.Equal(t, "", title)
+		assert.Equal(t, "Retry merges with backoff.", message)
+	})
+
+	t.Run("executionError", func(t *testing.T) {
+		_, _, err := calculateCommitMessage(ctx, pc, nil, MergeOption{Body: TemplateBody, Template: "{{.Missing}}"})
+		assert.Error(t, err)
+	})
+}
+
+func TestMergeOptionValidate(t *testing.T) {
+	assert.NoError(t, MergeOption{Body: SummarizeCommits}.validate())
+	assert.NoError(t, MergeOption{Body: TemplateBody, Template: "{{.Body}}", Title: "{{.Title}}"}.validate())
+	assert.Error(t, MergeOption{Body: TemplateBody}.validate())
+	assert.Error(t, MergeOption{Body: TemplateBody, Template: "{{.Body"}.validate())
+	assert.Error(t, MergeOption{Title: "{{if}}"}.validate())
+}
@@ -17,12 +17,13 @@ package bulldozer
 import (
 	"context"
 	"fmt"
-	"net/http"
 
 	"github.com/google/go-github/github"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"gopkg.in/yaml.v2"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
 )
 
 type FetchedConfig struct {
@@ -31,6 +32,12 @@ type FetchedConfig struct {
 	Ref    string
 	Config *Config
 	Error  error
+
+	// Path is the path of the configuration file that was used, if any
+	Path string
+	// Version is the version of the configuration file format that was
+	// used; v0 files are converted to v1 configuration
+	Version int
 }
 
 func (fc FetchedConfig) Missing() bool {
@@ -46,7 +53,10 @@ func (fc FetchedConfig) Invalid() bool {
 }
 
 func (fc FetchedConfig) String() string {
-	return fmt.Sprintf("%s/%s ref=%s", fc.Owner, fc.Repo, fc.Ref)
+	if fc.Path == "" {
+		return fmt.Sprintf("%s/%s ref=%s", fc.Owner, fc.Repo, fc.Ref)
+	}
+	return fmt.Sprintf("%s/%s ref=%s path=%s version=v%d", fc.Owner, fc.Repo, fc.Ref, fc.Path, fc.Version)
 }
 
 type ConfigFetcher struct {
@@ -81,6 +91,8 @@ func (cf *ConfigFetcher) ConfigForPR(ctx context.Context, client *github.Client,
 			logger.Debug().Msgf("v1 config is invalid")
 		} else {
 			fc.Config = config
+			fc.Path = cf.configurationV1Path
+			fc.Version = 1
 			return fc, nil
 		}
 	}
@@ -103,6 +115,8 @@ func (cf *ConfigFetcher) ConfigForPR(ctx context.Context, client *github.Client,
 		logger.Debug().Msgf("found v0 configuration at %s with merge method %s", configV0Path, config.Merge.Method)
 
 		fc.Config = config
+		fc.Path = configV0Path
+		fc.Version = 0
 		return fc, nil
 	}
 
@@ -115,29 +129,7 @@ func (cf *ConfigFetcher) fetchConfigContents(ctx context.Context, client *github
 	logger := zerolog.Ctx(ctx)
 	logger.Debug().Str("path", configPath).Str("ref", ref).Msg("Attempting to fetch configuration definition")
 
-	opts := &github.RepositoryContentGetOptions{
-		Ref: ref,
-	}
-
-	file, _, _, err := client.Repositories.GetContents(ctx, owner, repo, configPath, opts)
-	if err != nil {
-		if rerr, ok := err.(*github.ErrorResponse); ok && rerr.Response.StatusCode == http.StatusNotFound {
-			return nil, nil
-		}
-		return nil, errors.Wrapf(err, "failed to fetch content of %q", configPath)
-	}
-
-	// file will be nil if the ref contains a directory at the expected file path
-	if file == nil {
-		return nil, nil
-	}
-
-	content, err := file.GetContent()
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to decode content of %q", configPath)
-	}
-
-	return []byte(content), nil
+	return pull.FetchFileContents(ctx, client, owner, repo, ref, configPath)
 }
 
 func (cf *ConfigFetcher) unmarshalConfig(bytes []byte) (*Config, error) {
@@ -150,6 +142,19 @@ func (cf *ConfigFetcher) unmarshalConfig(bytes []byte) (*Config, error) {
 		return nil, errors.Errorf("unexpected version '%d', expected 1", config.Version)
 	}
 
+	for method, option := range config.Merge.Options {
+		if err := option.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s merge option", method)
+		}
+	}
+	for branch, options := range config.Merge.BranchOptions {
+		for method, option := range options {
+			if err := option.validate(); err != nil {
+				return nil, errors.Wrapf(err, "invalid %s merge option of branch %s", method, branch)
+			}
+		}
+	}
+
 	return &config, nil
 }
 
@@ -214,7 +219,7 @@ func (cf *ConfigFetcher) unmarshalConfigV0(bytes []byte) (*Config, error) {
 				DeleteAfterMerge: configv0.DeleteAfterMerge,
 				Method:           configv0.Strategy,
 				Options: map[MergeMethod]MergeOption{
-					configv0.Strategy: {PullRequestBody, "==COMMIT_MSG=="},
+					configv0.Strategy: {Body: PullRequestBody, MessageDelimiter: "==COMMIT_MSG=="},
 				},
 			},
 		}
@@ -21,6 +21,7 @@ const (
 	PullRequestBody  MessageStrategy = "pull_request_body"
 	SummarizeCommits MessageStrategy = "summarize_commits"
 	EmptyBody        MessageStrategy = "empty_body"
+	TemplateBody     MessageStrategy = "template"
 
 	MergeCommit    MergeMethod = "merge"
 	SquashAndMerge MergeMethod = "squash"
@@ -32,16 +33,100 @@ type Signals struct {
 	CommentSubstrings []string `yaml:"comment_substrings"`
 	Comments          []string `yaml:"comments"`
 	PRBodySubstrings  []string `yaml:"pr_body_substrings"`
+	TitleSubstrings   []string `yaml:"title_substrings"`
+	TitlePrefixes     []string `yaml:"title_prefixes"`
+
+	LabelPatterns   []Pattern `yaml:"label_patterns"`
+	CommentPatterns []Pattern `yaml:"comment_patterns"`
+	TitlePatterns   []Pattern `yaml:"title_patterns"`
+	PRBodyPatterns  []Pattern `yaml:"pr_body_patterns"`
+
+	// Authors are user logins and AuthorTeams are "<org>/<team-slug>"
+	// teams. AuthorAssociations are relationships of the author to the
+	// repository, such as "MEMBER" or "FIRST_TIME_CONTRIBUTOR".
+	Authors            []string `yaml:"authors"`
+	AuthorTeams        []string `yaml:"author_teams"`
+	AuthorAssociations []string `yaml:"author_associations"`
+
+	// ChangedFiles are patterns matched against the paths of the files
+	// changed by the pull request, with the same syntax as CODEOWNERS. A
+	// blacklist matches if any file matches a pattern, a whitelist only if
+	// every file matches a pattern.
+	ChangedFiles []PathPattern `yaml:"changed_files"`
+
+	// TrustedCommenters restricts the comment signals to comments by
+	// trusted users. It is not a signal by itself.
+	TrustedCommenters TrustedCommenters `yaml:"trusted_commenters"`
+}
+
+// TrustedCommenters defines whose comments count for comment signals. If
+// none of the fields are set, all comments count. Otherwise a comment counts
+// if its author satisfies any of the fields. The pull request body counts
+// as a comment by the author of the pull request.
+type TrustedCommenters struct {
+	// WritePermission trusts users with write or admin permission on the
+	// repository
+	WritePermission bool     `yaml:"write_permission"`
+	Users           []string `yaml:"users"`
+	// Teams are written as "<org>/<team-slug>"
+	Teams []string `yaml:"teams"`
+}
+
+// CommandConfig defines comments that act as commands. Comments are
+// processed in the order they were created and only the latest command
+// counts, so that a command can be retracted by a later one.
+type CommandConfig struct {
+	// Merge commands approve merging the pull request
+	Merge []string `yaml:"merge"`
+	// Hold commands blacklist the pull request
+	Hold []string `yaml:"hold"`
+	// Cancel commands retract earlier merge and hold commands
+	Cancel []string `yaml:"cancel"`
+
+	// SlashCommands enables the "/bulldozer merge" and "/bulldozer cancel"
+	// slash commands of users with write permission
+	SlashCommands bool `yaml:"slash_commands"`
+
+	// OverrideWhitelist lets a merge command satisfy the whitelist. Otherwise
+	// the whitelist applies as usual.
+	OverrideWhitelist bool `yaml:"override_whitelist"`
+
+	TrustedCommenters TrustedCommenters `yaml:"trusted_commenters"`
+}
+
+func (c *CommandConfig) Enabled() bool {
+	return c.SlashCommands || len(c.Merge)+len(c.Hold)+len(c.Cancel) > 0
+}
+
+// MergeEnabled returns true if merge commands are configured, either as
+// texts or as slash commands.
+func (c *CommandConfig) MergeEnabled() bool {
+	return c.SlashCommands || len(c.Merge) > 0
+}
+
+func (t *TrustedCommenters) Enabled() bool {
+	return t.WritePermission || len(t.Users)+len(t.Teams) > 0
 }
 
 func (s *Signals) Enabled() bool {
-	return len(s.Labels)+len(s.CommentSubstrings)+len(s.Comments)+len(s.PRBodySubstrings) > 0
+	return len(s.Labels)+len(s.CommentSubstrings)+len(s.Comments)+len(s.PRBodySubstrings)+
+		len(s.TitleSubstrings)+len(s.TitlePrefixes)+
+		len(s.LabelPatterns)+len(s.CommentPatterns)+len(s.TitlePatterns)+len(s.PRBodyPatterns)+
+		len(s.Authors)+len(s.AuthorTeams)+len(s.AuthorAssociations)+len(s.ChangedFiles) > 0
 }
 
 type MergeConfig struct {
 	Whitelist Signals `yaml:"whitelist"`
 	Blacklist Signals `yaml:"blacklist"`
 
+	// Rules must pass in addition to the whitelist, if they are set
+	Rules *Rule `yaml:"rules"`
+
+	Commands CommandConfig `yaml:"commands"`
+
+	// Draft pull requests are never merged unless AllowDrafts is set
+	AllowDrafts bool `yaml:"allow_drafts"`
+
 	DeleteAfterMerge bool `yaml:"delete_after_merge"`
 
 	Method  MergeMethod                 `yaml:"method"`
@@ -49,19 +134,97 @@ type MergeConfig struct {
 
 	BranchMethod map[string]MergeMethod `yaml:"branch_method"`
 
+	// BranchOptions replace the options of a merge method for pull requests
+	// that target a branch
+	BranchOptions map[string]map[MergeMethod]MergeOption `yaml:"branch_options"`
+
 	// Additional status checks that bulldozer should require
 	// (even if the branch protection settings doesn't require it)
 	RequiredStatuses []string `yaml:"required_statuses"`
+
+	// Conclusions of completed check runs that satisfy a required status
+	// check. Defaults to DefaultCheckConclusions if empty.
+	CheckConclusions []string `yaml:"check_conclusions"`
+
+	Reviews ReviewConfig `yaml:"reviews"`
+}
+
+// ResolveMethod returns the merge method for pull requests that target base.
+// Invalid methods resolve to MergeCommit.
+func (c MergeConfig) ResolveMethod(base string) MergeMethod {
+	method := c.Method
+	if branchMethod, ok := c.BranchMethod[base]; ok {
+		method = branchMethod
+	}
+
+	if !isValidMergeMethod(method) {
+		return MergeCommit
+	}
+	return method
+}
+
+// ResolveOption returns the options of a merge method for pull requests that
+// target base and true, or false if the method has no options.
+func (c MergeConfig) ResolveOption(base string, method MergeMethod) (MergeOption, bool) {
+	if option, ok := c.BranchOptions[base][method]; ok {
+		return option, true
+	}
+	option, ok := c.Options[method]
+	return option, ok
+}
+
+// ReviewConfig defines review rules that bulldozer checks before it attempts
+// a merge, in addition to the review requirements of branch protection.
+type ReviewConfig struct {
+	// Minimum number of approving reviews. The larger of this and the
+	// branch protection requirement applies.
+	MinApprovals int `yaml:"min_approvals"`
+
+	// If true, at least one approval must be for the head commit
+	RequireApprovalOnLatestCommit bool `yaml:"require_approval_on_latest_commit"`
+
+	// If true, approvals of earlier commits are not counted
+	IgnoreStaleApprovals bool `yaml:"ignore_stale_approvals"`
+
+	// If true, each changed file with an owner in the CODEOWNERS file of the
+	// base branch needs an approval from one of its owners. This is always
+	// the case if branch protection requires code owner reviews.
+	RequireCodeOwners bool `yaml:"require_code_owners"`
 }
 
+// DefaultCheckConclusions are the check run conclusions that satisfy a
+// required status check unless configured otherwise.
+var DefaultCheckConclusions = []string{"success", "neutral", "skipped"}
+
 type MergeOption struct {
 	Body             MessageStrategy `yaml:"body"`
 	MessageDelimiter string          `yaml:"message_delimiter"`
+
+	// Template is the text/template of the commit message if Body is
+	// TemplateBody. See CommitMessageData for the available data.
+	Template string `yaml:"template"`
+
+	// Title is the text/template of the commit title. If empty, GitHub
+	// chooses the title.
+	Title string `yaml:"title"`
+
+	// CoAuthoredBy adds a Co-authored-by trailer for each author and
+	// committer of the commits of the pull request
+	CoAuthoredBy bool `yaml:"co_authored_by"`
+
+	// ReviewedBy adds a Reviewed-by trailer for each approving reviewer
+	ReviewedBy bool `yaml:"reviewed_by"`
 }
 
 type UpdateConfig struct {
 	Whitelist Signals `yaml:"whitelist"`
 	Blacklist Signals `yaml:"blacklist"`
+
+	// Rules must pass in addition to the whitelist, if they are set
+	Rules *Rule `yaml:"rules"`
+
+	// Draft pull requests are never updated unless AllowDrafts is set
+	AllowDrafts bool `yaml:"allow_drafts"`
 }
 
 type Config struct {
@@ -25,110 +25,291 @@ import (
 	"github.com/CyberhavenInc/bulldozer/pull"
 )
 
-// IsPRBlacklisted returns true if the PR is identified as blacklisted,
-// false otherwise. Additionally, a description of the reason will be returned.
-func IsPRBlacklisted(ctx context.Context, pullCtx pull.Context, config Signals) (bool, string, error) {
+// SignalMatch describes the signal that caused a pull request to be
+// blacklisted or whitelisted.
+type SignalMatch struct {
+	// Signal is the configuration key of the signal, e.g. "labels"
+	Signal string `json:"signal"`
+	// Source is the part of the pull request that matched, e.g. "label"
+	Source string `json:"source"`
+	// Value is the configured value that matched
+	Value string `json:"value"`
+	// Reason is a human readable description of the match
+	Reason string `json:"reason"`
+}
+
+func newSignalMatch(list, signal, source, value string) *SignalMatch {
+	description := map[string]string{
+		"labels":              "labels",
+		"comments":            "comments",
+		"comment_substrings":  "comment substrings",
+		"pr_body_substrings":  "substrings",
+		"title_substrings":    "title substrings",
+		"title_prefixes":      "title prefixes",
+		"label_patterns":      "label patterns",
+		"comment_patterns":    "comment patterns",
+		"title_patterns":      "title patterns",
+		"pr_body_patterns":    "body patterns",
+		"authors":             "authors",
+		"author_teams":        "author teams",
+		"author_associations": "author associations",
+		"changed_files":       "changed files",
+	}[signal]
+
+	return &SignalMatch{
+		Signal: signal,
+		Source: source,
+		Value:  value,
+		Reason: fmt.Sprintf("PR %s matches one of specified %s %s: %q", source, list, description, value),
+	}
+}
+
+// matchSignals returns the first signal in config that matches the pull
+// request, or nil if no signal matches. list is either "blacklist" or
+// "whitelist" and is used to describe the match.
+func matchSignals(ctx context.Context, pullCtx pull.Context, config Signals, list string) (*SignalMatch, string, error) {
 	labels, err := pullCtx.Labels(ctx)
 	if err != nil {
-		return true, "unable to list PR labels", err
+		return nil, "unable to list PR labels", err
 	}
 
 	if inSlice, idx := anyInSliceCaseInsensitive(labels, config.Labels); inSlice {
-		return true, fmt.Sprintf("PR label matches one of specified blacklist labels: %q", config.Labels[idx]), nil
+		return newSignalMatch(list, "labels", "label", config.Labels[idx]), "", nil
+	}
+
+	for _, pattern := range config.LabelPatterns {
+		for _, label := range labels {
+			if pattern.MatchesFold(label) {
+				return newSignalMatch(list, "label_patterns", "label", pattern.String()), "", nil
+			}
+		}
 	}
 
 	body, err := pullCtx.Body(ctx)
 	if err != nil {
-		return true, "unable to list PR body", err
+		return nil, "unable to list PR body", err
 	}
 
-	comments, err := pullCtx.Comments(ctx)
+	comments, bodyTrusted, reason, err := trustedCommentBodies(ctx, pullCtx, config)
 	if err != nil {
-		return true, "unable to list PR comments", err
+		return nil, reason, err
 	}
 
 	if inSlice, idx := anyInSlice(comments, config.Comments); inSlice {
-		return true, fmt.Sprintf("PR comment matches one of specified blacklist comments: %q", config.Comments[idx]), nil
+		return newSignalMatch(list, "comments", "comment", config.Comments[idx]), "", nil
 	}
 
-	for _, blacklistedComment := range config.Comments {
-		if blacklistedComment == body {
-			return true, fmt.Sprintf("PR body matches one of specified blacklist comments: %q", blacklistedComment), nil
+	for _, comment := range config.Comments {
+		if bodyTrusted && comment == body {
+			return newSignalMatch(list, "comments", "body", comment), "", nil
 		}
 	}
 
-	for _, blacklistedSubstring := range config.CommentSubstrings {
+	for _, substring := range config.CommentSubstrings {
 		for _, comment := range comments {
-			if strings.Contains(comment, blacklistedSubstring) {
-				return true, fmt.Sprintf("PR comment matches one of specified blacklist comment substrings: %q", blacklistedSubstring), nil
+			if strings.Contains(comment, substring) {
+				return newSignalMatch(list, "comment_substrings", "comment", substring), "", nil
 			}
 		}
 
-		if strings.Contains(body, blacklistedSubstring) {
-			return true, fmt.Sprintf("PR body matches one of specified blacklist comment substrings: %q", blacklistedSubstring), nil
+		if bodyTrusted && strings.Contains(body, substring) {
+			return newSignalMatch(list, "comment_substrings", "body", substring), "", nil
 		}
 	}
 
-	for _, blacklistedSubstring := range config.PRBodySubstrings {
-		if strings.Contains(body, blacklistedSubstring) {
-			return true, fmt.Sprintf("PR body matches one of specified blacklist substrings: %q", blacklistedSubstring), nil
+	for _, substring := range config.PRBodySubstrings {
+		if strings.Contains(body, substring) {
+			return newSignalMatch(list, "pr_body_substrings", "body", substring), "", nil
 		}
 	}
 
-	return false, "no matching blacklist found", nil
-}
+	for _, pattern := range config.CommentPatterns {
+		for _, comment := range comments {
+			if pattern.Matches(comment) {
+				return newSignalMatch(list, "comment_patterns", "comment", pattern.String()), "", nil
+			}
+		}
+	}
 
-// IsPRWhitelisted returns true if the PR is identified as whitelisted,
-// false otherwise. Additionally, a description of the reason will be returned.
-func IsPRWhitelisted(ctx context.Context, pullCtx pull.Context, config Signals) (bool, string, error) {
-	labels, err := pullCtx.Labels(ctx)
-	if err != nil {
-		return false, "unable to list PR labels", err
+	for _, pattern := range config.PRBodyPatterns {
+		if pattern.Matches(body) {
+			return newSignalMatch(list, "pr_body_patterns", "body", pattern.String()), "", nil
+		}
 	}
 
-	if inSlice, idx := anyInSliceCaseInsensitive(labels, config.Labels); inSlice {
-		return true, fmt.Sprintf("PR label matches one of specified whitelist labels: %q", config.Labels[idx]), nil
+	if len(config.TitleSubstrings)+len(config.TitlePrefixes)+len(config.TitlePatterns) > 0 {
+		title, err := pullCtx.Title(ctx)
+		if err != nil {
+			return nil, "unable to get PR title", err
+		}
+
+		for _, substring := range config.TitleSubstrings {
+			if strings.Contains(title, substring) {
+				return newSignalMatch(list, "title_substrings", "title", substring), "", nil
+			}
+		}
+
+		for _, prefix := range config.TitlePrefixes {
+			if strings.HasPrefix(title, prefix) {
+				return newSignalMatch(list, "title_prefixes", "title", prefix), "", nil
+			}
+		}
+
+		for _, pattern := range config.TitlePatterns {
+			if pattern.Matches(title) {
+				return newSignalMatch(list, "title_patterns", "title", pattern.String()), "", nil
+			}
+		}
 	}
 
-	body, err := pullCtx.Body(ctx)
-	if err != nil {
-		return false, "unable to list PR body", err
+	if len(config.Authors)+len(config.AuthorTeams)+len(config.AuthorAssociations) > 0 {
+		author, err := pullCtx.Author(ctx)
+		if err != nil {
+			return nil, "unable to get PR author", err
+		}
+
+		if inSlice, idx := anyInSliceCaseInsensitive([]string{author}, config.Authors); inSlice {
+			return newSignalMatch(list, "authors", "author", config.Authors[idx]), "", nil
+		}
+
+		association, err := pullCtx.AuthorAssociation(ctx)
+		if err != nil {
+			return nil, "unable to get PR author association", err
+		}
+
+		if inSlice, idx := anyInSliceCaseInsensitive([]string{association}, config.AuthorAssociations); inSlice {
+			return newSignalMatch(list, "author_associations", "author association", config.AuthorAssociations[idx]), "", nil
+		}
+
+		for _, team := range config.AuthorTeams {
+			members, err := pullCtx.TeamMembers(ctx, team)
+			if err != nil {
+				return nil, "unable to list members of team " + team, err
+			}
+			if inSlice, _ := anyInSliceCaseInsensitive([]string{author}, members); inSlice {
+				return newSignalMatch(list, "author_teams", "author", team), "", nil
+			}
+		}
 	}
 
+	if len(config.ChangedFiles) > 0 {
+		files, err := pullCtx.ChangedFiles(ctx)
+		if err != nil {
+			// an incomplete list can't show that every file matches, but the
+			// blacklist fails closed since a missing file might match
+			if list == "whitelist" && errors.Cause(err) == pull.ErrTooManyChangedFiles {
+				return nil, fmt.Sprintf("no matching %s found, the pull request changes more than %d files", list, pull.MaxChangedFiles), nil
+			}
+			return nil, "unable to list PR changed files", err
+		}
+
+		if match := matchChangedFiles(list, files, config.ChangedFiles); match != nil {
+			return match, "", nil
+		}
+	}
+
+	return nil, fmt.Sprintf("no matching %s found", list), nil
+}
+
+// trustedCommentBodies returns the bodies of the comments that count for the
+// comment signals of config and whether the pull request body counts as a
+// comment. Commenters are only looked up if config uses comment signals.
+func trustedCommentBodies(ctx context.Context, pullCtx pull.Context, config Signals) ([]string, bool, string, error) {
 	comments, err := pullCtx.Comments(ctx)
 	if err != nil {
-		return false, "unable to list PR comments", err
+		return nil, false, "unable to list PR comments", err
 	}
 
-	if inSlice, idx := anyInSlice(comments, config.Comments); inSlice {
-		return true, fmt.Sprintf("PR comment matches one of specified whitelist comments: %q", config.Comments[idx]), nil
-	}
+	bodyTrusted := true
+	if config.TrustedCommenters.Enabled() && len(config.Comments)+len(config.CommentSubstrings)+len(config.CommentPatterns) > 0 {
+		trust := newCommenterTrust(pullCtx, config.TrustedCommenters)
 
-	for _, whitelistedComment := range config.Comments {
-		if whitelistedComment == body {
-			return true, fmt.Sprintf("PR body matches one of specified whitelist comments: %q", whitelistedComment), nil
+		comments, err = trust.trustedComments(ctx, comments)
+		if err != nil {
+			return nil, false, "unable to determine trusted commenters", err
+		}
+
+		author, err := pullCtx.Author(ctx)
+		if err != nil {
+			return nil, false, "unable to get PR author", err
+		}
+		if bodyTrusted, err = trust.trusts(ctx, author); err != nil {
+			return nil, false, "unable to determine trusted commenters", err
 		}
 	}
 
-	for _, whitelistedSubstring := range config.CommentSubstrings {
-		for _, comment := range comments {
-			if strings.Contains(comment, whitelistedSubstring) {
-				return true, fmt.Sprintf("PR comment matches one of specified whitelist comment substrings: %q", whitelistedSubstring), nil
+	bodies := make([]string, len(comments))
+	for i, comment := range comments {
+		bodies[i] = comment.Body
+	}
+	return bodies, bodyTrusted, "", nil
+}
+
+// matchChangedFiles matches the changed files of a pull request against
+// patterns. A blacklist matches if any file matches, while a whitelist
+// matches only if every file matches, so that a pull request touching
+// anything else is not whitelisted.
+func matchChangedFiles(list string, files []string, patterns []PathPattern) *SignalMatch {
+	matching := func(file string) *PathPattern {
+		for i := range patterns {
+			if patterns[i].Matches(file) {
+				return &patterns[i]
 			}
 		}
+		return nil
+	}
 
-		if strings.Contains(body, whitelistedSubstring) {
-			return true, fmt.Sprintf("PR body matches one of specified whitelist comment substrings: %q", whitelistedSubstring), nil
+	if list == "blacklist" {
+		for _, file := range files {
+			if pattern := matching(file); pattern != nil {
+				return newSignalMatch(list, "changed_files", "changed file "+file, pattern.String())
+			}
 		}
+		return nil
 	}
 
-	for _, whitelistedSubstring := range config.PRBodySubstrings {
-		if strings.Contains(body, whitelistedSubstring) {
-			return true, fmt.Sprintf("PR body matches one of specified whitelist substrings: %q", whitelistedSubstring), nil
+	if len(files) == 0 {
+		return nil
+	}
+	for _, file := range files {
+		if matching(file) == nil {
+			return nil
 		}
 	}
 
-	return false, "no matching whitelist found", nil
+	values := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		values[i] = pattern.String()
+	}
+	match := newSignalMatch(list, "changed_files", "changed files", strings.Join(values, ", "))
+	match.Reason = fmt.Sprintf("all PR changed files match specified %s changed files: %q", list, match.Value)
+	return match
+}
+
+// IsPRBlacklisted returns true if the PR is identified as blacklisted,
+// false otherwise. Additionally, a description of the reason will be returned.
+func IsPRBlacklisted(ctx context.Context, pullCtx pull.Context, config Signals) (bool, string, error) {
+	match, reason, err := matchSignals(ctx, pullCtx, config, "blacklist")
+	if err != nil {
+		return true, reason, err
+	}
+	if match != nil {
+		return true, match.Reason, nil
+	}
+	return false, reason, nil
+}
+
+// IsPRWhitelisted returns true if the PR is identified as whitelisted,
+// false otherwise. Additionally, a description of the reason will be returned.
+func IsPRWhitelisted(ctx context.Context, pullCtx pull.Context, config Signals) (bool, string, error) {
+	match, reason, err := matchSignals(ctx, pullCtx, config, "whitelist")
+	if err != nil {
+		return false, reason, err
+	}
+	if match != nil {
+		return true, match.Reason, nil
+	}
+	return false, reason, nil
 }
 
 func anyInSlice(testValues []string, elements []string) (bool, int) {
@@ -175,52 +356,367 @@ func setDifference(set1, set2 []string) []string {
 	return result
 }
 
-// ShouldMergePR TODO: may want to return a richer type than bool
-func ShouldMergePR(ctx context.Context, pullCtx pull.Context, mergeConfig MergeConfig) (bool, error) {
-	logger := zerolog.Ctx(ctx)
+// EvaluationResult explains why a pull request should or should not be
+// merged or updated.
+type EvaluationResult struct {
+	// Allowed is true if the pull request should be merged or updated
+	Allowed bool `json:"allowed"`
+	// Reason is a human readable summary of the decision
+	Reason string `json:"reason"`
+	// HeadSHA is the head commit the merge evaluation applies to; a merge
+	// must not include other commits
+	HeadSHA string `json:"head_sha,omitempty"`
+
+	// Blacklist is the blacklist signal that matched, if any
+	Blacklist *SignalMatch `json:"blacklist,omitempty"`
+	// Whitelist is the whitelist signal that matched, if any
+	Whitelist *SignalMatch `json:"whitelist,omitempty"`
+	// Draft is true if the pull request is a draft and drafts are not allowed
+	Draft bool `json:"draft,omitempty"`
+	// Command is the latest command of the pull request, if any
+	Command *CommandMatch `json:"command,omitempty"`
+	// Rules is the trace of the configured rules, if they were evaluated
+	Rules *RuleTrace `json:"rules,omitempty"`
+
+	// RequiredStatuses are the status checks that must succeed before the
+	// pull request is merged
+	RequiredStatuses []string `json:"required_statuses,omitempty"`
+	// MissingStatuses are the required status checks that have not
+	// succeeded yet
+	MissingStatuses []string `json:"missing_statuses,omitempty"`
+	// PendingStatuses are the missing status checks that are still running
+	PendingStatuses []string `json:"pending_statuses,omitempty"`
+	// FailedStatuses are the missing status checks that failed
+	FailedStatuses []string `json:"failed_statuses,omitempty"`
+
+	// RequiredApprovals is the number of approving reviews required
+	RequiredApprovals int `json:"required_approvals,omitempty"`
+	// ApprovedBy are the reviewers whose approval counts
+	ApprovedBy []string `json:"approved_by,omitempty"`
+	// ChangesRequestedBy are the reviewers who requested changes
+	ChangesRequestedBy []string `json:"changes_requested_by,omitempty"`
+	// MissingCodeOwners are the owners of changed files that did not
+	// approve; each element lists the alternative owners of a file
+	MissingCodeOwners []string `json:"missing_code_owners,omitempty"`
+	// Reviews describes why the reviews do not allow a merge, if they don't
+	Reviews string `json:"reviews,omitempty"`
+
+	// Error is set if the evaluation could not be completed
+	Error error `json:"-"`
+}
 
-	if mergeConfig.Blacklist.Enabled() {
-		blacklisted, reason, err := IsPRBlacklisted(ctx, pullCtx, mergeConfig.Blacklist)
+func (r EvaluationResult) String() string {
+	return r.Reason
+}
+
+// Summary returns a short description of the result that is suitable for a
+// commit status.
+func (r EvaluationResult) Summary() string {
+	switch {
+	case r.Error != nil:
+		return "unable to evaluate: " + r.Reason
+	case r.Allowed:
+		return "ready to merge"
+	case r.Draft:
+		return "pull request is a draft"
+	case r.Command != nil && r.Command.Command == CommandHold:
+		return "on hold by " + r.Command.Author
+	case r.Blacklist != nil:
+		return fmt.Sprintf("blacklisted by %s %s", r.Blacklist.Source, r.Blacklist.Value)
+	case r.Rules != nil && !r.Rules.Passed:
+		return "rules are not satisfied"
+	case len(r.FailedStatuses) > 0:
+		return strings.Join(r.FailedStatuses, ", ") + " failed"
+	case len(r.MissingStatuses) > 0:
+		return "waiting for " + strings.Join(r.MissingStatuses, ", ")
+	case r.Reviews != "":
+		return "review required: " + r.Reviews
+	}
+	return r.Reason
+}
+
+// signalConfig is the part of a merge or update configuration that decides
+// which pull requests are considered.
+type signalConfig struct {
+	Blacklist Signals
+	Whitelist Signals
+	// Rules must pass in addition to the whitelist, if not nil
+	Rules *Rule
+	// Commands are evaluated before the blacklist; a hold command blacklists
+	// the pull request and, if the commands override the whitelist, a merge
+	// command satisfies it. Without a whitelist, a merge command is required
+	// if merge commands are configured.
+	// Commands are ignored if nil.
+	Commands    *CommandConfig
+	AllowDrafts bool
+}
+
+// evaluateSignals applies the signals of a configuration to a pull request.
+// It returns false and a result that is not allowed if the evaluation is
+// decided by the signals; otherwise the returned result records the matched
+// whitelist signal and evaluation should continue. Draft pull requests are
+// implicitly blacklisted unless drafts are allowed.
+func evaluateSignals(ctx context.Context, pullCtx pull.Context, config signalConfig) (EvaluationResult, bool) {
+	var result EvaluationResult
+
+	if !config.AllowDrafts {
+		draft, err := pullCtx.IsDraft(ctx)
 		if err != nil {
-			return false, errors.Wrap(err, "failed to determine if pull request is blacklisted")
+			result.Reason = "unable to determine if PR is a draft"
+			result.Error = errors.Wrap(err, "failed to determine if pull request is a draft")
+			return result, false
 		}
-		if blacklisted {
-			logger.Debug().Msgf("%s is deemed not mergeable because blacklisting is enabled and %s", pullCtx.Locator(), reason)
-			return false, nil
+		if draft {
+			result.Draft = true
+			result.Reason = "the pull request is a draft"
+			return result, false
 		}
 	}
 
-	if mergeConfig.Whitelist.Enabled() {
-		whitelisted, reason, err := IsPRWhitelisted(ctx, pullCtx, mergeConfig.Whitelist)
+	var command *CommandMatch
+	if config.Commands != nil {
+		var err error
+		if command, err = LatestCommand(ctx, pullCtx, *config.Commands); err != nil {
+			result.Reason = "unable to determine the latest command"
+			result.Error = err
+			return result, false
+		}
+	}
+	result.Command = command
+	if command != nil && command.Command == CommandHold {
+		result.Reason = fmt.Sprintf("%s put the pull request on hold with %q", command.Author, command.Text)
+		return result, false
+	}
+
+	if config.Blacklist.Enabled() {
+		match, reason, err := matchSignals(ctx, pullCtx, config.Blacklist, "blacklist")
+		if err != nil {
+			result.Reason = reason
+			result.Error = errors.Wrap(err, "failed to determine if pull request is blacklisted")
+			return result, false
+		}
+		if match != nil {
+			result.Blacklist = match
+			result.Reason = fmt.Sprintf("blacklisting is enabled and %s", match.Reason)
+			return result, false
+		}
+	}
+
+	overridden := command != nil && config.Commands.OverrideWhitelist
+	if config.Whitelist.Enabled() && !overridden {
+		match, reason, err := matchSignals(ctx, pullCtx, config.Whitelist, "whitelist")
+		if err != nil {
+			result.Reason = reason
+			result.Error = errors.Wrap(err, "failed to determine if pull request is whitelisted")
+			return result, false
+		}
+		if match == nil {
+			result.Reason = "whitelisting is enabled and no whitelist signal detected"
+			return result, false
+		}
+		result.Whitelist = match
+	}
+
+	if config.Commands != nil && config.Commands.MergeEnabled() && !config.Whitelist.Enabled() && command == nil {
+		result.Reason = "commands are enabled and no merge command detected"
+		return result, false
+	}
+
+	if config.Rules != nil {
+		trace, err := EvaluateRule(ctx, pullCtx, *config.Rules)
+		result.Rules = &trace
 		if err != nil {
-			return false, errors.Wrap(err, "failed to determine if pull request is whitelisted")
+			result.Reason = "unable to evaluate rules"
+			result.Error = errors.Wrap(err, "failed to evaluate rules")
+			return result, false
 		}
-		if !whitelisted {
-			logger.Debug().Msgf("%s is deemed not mergeable because whitelisting is enabled and no whitelist signal detected", pullCtx.Locator())
-			return false, nil
+		if !trace.Passed {
+			result.Reason = "rules are not satisfied"
+			return result, false
 		}
+	}
+
+	return result, true
+}
+
+// statusChecks is the state of the required status checks of a pull request.
+type statusChecks struct {
+	required []string
+	// missing are the required checks that did not succeed
+	missing []string
+	// pending are the required checks that are reported but still running
+	pending []string
+	// failed are the required checks that failed or errored
+	failed []string
+}
+
+// evaluateStatusChecks determines the state of the status checks required by
+// branch protection and by the configuration. Commit statuses and check runs
+// both count; a check reported by both succeeds if either succeeds.
+func evaluateStatusChecks(ctx context.Context, pullCtx pull.Context, mergeConfig MergeConfig) (statusChecks, error) {
+	var checks statusChecks
 
-		logger.Debug().Msgf("%s is whitelisted because whitelisting is enabled and %s", pullCtx.Locator(), reason)
+	required, err := RequiredStatusChecks(ctx, pullCtx, mergeConfig)
+	if err != nil {
+		return checks, err
+	}
+	checks.required = required
+
+	statuses, err := pullCtx.Statuses(ctx)
+	if err != nil {
+		return checks, errors.Wrap(err, "failed to determine current status checks")
 	}
 
+	checkRuns, err := pullCtx.CheckRuns(ctx)
+	if err != nil {
+		return checks, errors.Wrap(err, "failed to determine current check runs")
+	}
+
+	states := make(map[string]string)
+	for _, status := range statuses {
+		setStatusState(states, status.Context, status.State)
+	}
+	for _, run := range checkRuns {
+		setStatusState(states, run.Name, CheckRunState(run, mergeConfig.CheckConclusions))
+	}
+
+	for _, name := range setDifference(checks.required, nil) {
+		switch states[name] {
+		case pull.StatusSuccess:
+			continue
+		case pull.StatusFailure, pull.StatusError:
+			checks.failed = append(checks.failed, name)
+		case pull.StatusPending:
+			checks.pending = append(checks.pending, name)
+		}
+		checks.missing = append(checks.missing, name)
+	}
+
+	return checks, nil
+}
+
+// RequiredStatusChecks returns the status checks that must succeed before a
+// pull request is merged: the checks required by branch protection followed
+// by the checks required by the configuration.
+func RequiredStatusChecks(ctx context.Context, pullCtx pull.Context, mergeConfig MergeConfig) ([]string, error) {
 	requiredStatuses, err := pullCtx.RequiredStatuses(ctx)
 	if err != nil {
-		return false, errors.Wrap(err, "failed to determine required Github status checks")
+		return nil, errors.Wrap(err, "failed to determine required Github status checks")
 	}
-	requiredStatuses = append(requiredStatuses, mergeConfig.RequiredStatuses...)
 
-	successStatuses, err := pullCtx.CurrentSuccessStatuses(ctx)
+	// the branch protection statuses may be cached by the context, so
+	// appending to them must not write to their backing array
+	required := make([]string, 0, len(requiredStatuses)+len(mergeConfig.RequiredStatuses))
+	required = append(required, requiredStatuses...)
+	return append(required, mergeConfig.RequiredStatuses...), nil
+}
+
+// FailedStatusChecks returns the required status checks that failed on the
+// head commit of a pull request. Updating such a pull request again will not
+// help until new commits are pushed.
+func FailedStatusChecks(ctx context.Context, pullCtx pull.Context, mergeConfig MergeConfig) ([]string, error) {
+	checks, err := evaluateStatusChecks(ctx, pullCtx, mergeConfig)
+	return checks.failed, err
+}
+
+var statusStateRank = map[string]int{
+	pull.StatusPending: 1,
+	pull.StatusError:   2,
+	pull.StatusFailure: 2,
+	pull.StatusSuccess: 3,
+}
+
+func setStatusState(states map[string]string, name, state string) {
+	if statusStateRank[state] > statusStateRank[states[name]] {
+		states[name] = state
+	}
+}
+
+// CheckRunState maps a check run to a commit status state. Completed check
+// runs succeed if their conclusion is one of conclusions, or one of
+// DefaultCheckConclusions if conclusions is empty.
+func CheckRunState(run pull.CheckRun, conclusions []string) string {
+	if run.Status != "completed" {
+		return pull.StatusPending
+	}
+
+	if len(conclusions) == 0 {
+		conclusions = DefaultCheckConclusions
+	}
+	for _, conclusion := range conclusions {
+		if strings.EqualFold(run.Conclusion, conclusion) {
+			return pull.StatusSuccess
+		}
+	}
+	return pull.StatusFailure
+}
+
+// ShouldMergePR evaluates whether a pull request should be merged. The
+// returned error is also recorded in the result.
+func ShouldMergePR(ctx context.Context, pullCtx pull.Context, mergeConfig MergeConfig) (EvaluationResult, error) {
+	logger := zerolog.Ctx(ctx)
+
+	result, ok := evaluateSignals(ctx, pullCtx, signalConfig{
+		Blacklist:   mergeConfig.Blacklist,
+		Whitelist:   mergeConfig.Whitelist,
+		Rules:       mergeConfig.Rules,
+		Commands:    &mergeConfig.Commands,
+		AllowDrafts: mergeConfig.AllowDrafts,
+	})
+	result.HeadSHA = pullCtx.HeadSHA()
+	if result.Error != nil {
+		return result, result.Error
+	}
+	if !ok {
+		logger.Debug().Msgf("%s is deemed not mergeable because %s", pullCtx.Locator(), result.Reason)
+		return result, nil
+	}
+	if result.Whitelist != nil {
+		logger.Debug().Msgf("%s is whitelisted because whitelisting is enabled and %s", pullCtx.Locator(), result.Whitelist.Reason)
+	}
+
+	checks, err := evaluateStatusChecks(ctx, pullCtx, mergeConfig)
 	if err != nil {
-		return false, errors.Wrap(err, "failed to determine currently successful status checks")
+		result.Reason = "unable to determine status checks"
+		result.Error = err
+		return result, result.Error
+	}
+	result.RequiredStatuses = checks.required
+	result.MissingStatuses = checks.missing
+	result.PendingStatuses = checks.pending
+	result.FailedStatuses = checks.failed
+
+	if len(checks.failed) > 0 {
+		result.Reason = fmt.Sprintf("of failed status checks: [%s]", strings.Join(checks.failed, ","))
+		logger.Debug().Msgf("%s is deemed not mergeable because %s", pullCtx.Locator(), result.Reason)
+		return result, nil
 	}
 
-	unsatisfiedStatuses := setDifference(requiredStatuses, successStatuses)
-	if len(unsatisfiedStatuses) > 0 {
-		logger.Debug().Msgf("%s is deemed not mergeable because of unfulfilled status checks: [%s]", pullCtx.Locator(), strings.Join(unsatisfiedStatuses, ","))
-		return false, nil
+	if len(checks.missing) > 0 {
+		result.Reason = fmt.Sprintf("of unfulfilled status checks: [%s]", strings.Join(checks.missing, ","))
+		logger.Debug().Msgf("%s is deemed not mergeable because %s", pullCtx.Locator(), result.Reason)
+		return result, nil
 	}
 
-	// Ignore required reviews and try a merge (which may fail with a 4XX).
+	reviews, err := evaluateReviews(ctx, pullCtx, mergeConfig.Reviews)
+	if err != nil {
+		result.Reason = "unable to determine review status"
+		result.Error = err
+		return result, result.Error
+	}
+	result.RequiredApprovals = reviews.required
+	result.ApprovedBy = reviews.approvedBy
+	result.ChangesRequestedBy = reviews.changesRequested
+	result.MissingCodeOwners = reviews.missingOwners
+
+	if reviews.unsatisfied != "" {
+		result.Reason = "of unsatisfied reviews: " + reviews.unsatisfied
+		result.Reviews = reviews.unsatisfied
+		logger.Debug().Msgf("%s is deemed not mergeable because %s", pullCtx.Locator(), result.Reason)
+		return result, nil
+	}
 
-	return true, nil
+	result.Allowed = true
+	result.Reason = "all merge conditions are satisfied"
+	return result, nil
 }
@@ -22,6 +22,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/CyberhavenInc/bulldozer/pull"
 	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
 )
 
@@ -279,6 +280,268 @@ func TestSimpleXListed(t *testing.T) {
 	})
 }
 
+func TestTitleXListed(t *testing.T) {
+	mergeConfig := MergeConfig{
+		Whitelist: Signals{
+			TitleSubstrings: []string{"(merge when ready)"},
+			TitlePrefixes:   []string{"[READY]"},
+		},
+		Blacklist: Signals{
+			TitleSubstrings: []string{"DO NOT MERGE"},
+			TitlePrefixes:   []string{"[WIP]"},
+		},
+	}
+
+	ctx := context.Background()
+
+	t.Run("titlePrefixCausesBlacklist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			TitleValue: "[WIP] Add feature",
+		}
+
+		actualBlacklist, reason, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.Nil(t, err)
+		assert.True(t, actualBlacklist)
+		assert.Equal(t, `PR title matches one of specified blacklist title prefixes: "[WIP]"`, reason)
+	})
+
+	t.Run("titlePrefixMustBeAtStart", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			TitleValue: "Remove [WIP] marker",
+		}
+
+		actualBlacklist, _, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.Nil(t, err)
+		assert.False(t, actualBlacklist)
+	})
+
+	t.Run("titleSubstringCausesBlacklist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			TitleValue: "Add feature (DO NOT MERGE)",
+		}
+
+		actualBlacklist, reason, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.Nil(t, err)
+		assert.True(t, actualBlacklist)
+		assert.Equal(t, `PR title matches one of specified blacklist title substrings: "DO NOT MERGE"`, reason)
+	})
+
+	t.Run("titlePrefixCausesWhitelist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			TitleValue: "[READY] Add feature",
+		}
+
+		actualWhitelist, reason, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+		assert.Equal(t, `PR title matches one of specified whitelist title prefixes: "[READY]"`, reason)
+	})
+
+	t.Run("titleSubstringCausesWhitelist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			TitleValue: "Add feature (merge when ready)",
+		}
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+	})
+
+	t.Run("errTitleFailsClosedBlacklist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			TitleErrValue: errors.New("failure"),
+		}
+
+		actualBlacklist, _, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.NotNil(t, err)
+		assert.True(t, actualBlacklist)
+	})
+}
+
+func TestAuthorXListed(t *testing.T) {
+	mergeConfig := MergeConfig{
+		Whitelist: Signals{
+			Authors:     []string{"dependabot[bot]"},
+			AuthorTeams: []string{"org/bots"},
+		},
+		Blacklist: Signals{
+			AuthorAssociations: []string{"CONTRIBUTOR", "FIRST_TIME_CONTRIBUTOR"},
+		},
+	}
+
+	ctx := context.Background()
+
+	t.Run("authorCausesWhitelist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			AuthorValue: "Dependabot[bot]",
+		}
+
+		actualWhitelist, reason, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+		assert.Equal(t, `PR author matches one of specified whitelist authors: "dependabot[bot]"`, reason)
+	})
+
+	t.Run("authorTeamCausesWhitelist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			AuthorValue:      "renovate",
+			TeamMembersValue: map[string][]string{"org/bots": {"Renovate"}},
+		}
+
+		actualWhitelist, reason, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+		assert.Equal(t, `PR author matches one of specified whitelist author teams: "org/bots"`, reason)
+	})
+
+	t.Run("noMatchingAuthor", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			AuthorValue:      "octocat",
+			TeamMembersValue: map[string][]string{"org/bots": {"renovate"}},
+		}
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.Nil(t, err)
+		assert.False(t, actualWhitelist)
+	})
+
+	t.Run("associationCausesBlacklist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			AuthorValue:            "octocat",
+			AuthorAssociationValue: "FIRST_TIME_CONTRIBUTOR",
+		}
+
+		actualBlacklist, reason, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.Nil(t, err)
+		assert.True(t, actualBlacklist)
+		assert.Equal(t, `PR author association matches one of specified blacklist author associations: "FIRST_TIME_CONTRIBUTOR"`, reason)
+	})
+
+	t.Run("memberIsNotBlacklisted", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			AuthorValue:            "octocat",
+			AuthorAssociationValue: "MEMBER",
+		}
+
+		actualBlacklist, _, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.Nil(t, err)
+		assert.False(t, actualBlacklist)
+	})
+
+	t.Run("errTeamMembersFailsClosedWhitelist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			AuthorValue:         "renovate",
+			TeamMembersErrValue: errors.New("failure"),
+		}
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.NotNil(t, err)
+		assert.False(t, actualWhitelist)
+	})
+
+	t.Run("errAuthorFailsClosedBlacklist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			AuthorErrValue: errors.New("failure"),
+		}
+
+		actualBlacklist, _, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.NotNil(t, err)
+		assert.True(t, actualBlacklist)
+	})
+}
+
+func TestChangedFilesXListed(t *testing.T) {
+	patterns := func(sources ...string) []PathPattern {
+		var result []PathPattern
+		for _, source := range sources {
+			result = append(result, NewPathPattern(source))
+		}
+		return result
+	}
+
+	mergeConfig := MergeConfig{
+		Whitelist: Signals{
+			ChangedFiles: patterns("docs/", "*.md"),
+		},
+		Blacklist: Signals{
+			ChangedFiles: patterns("migrations/", "infra/terraform/*"),
+		},
+	}
+
+	ctx := context.Background()
+
+	t.Run("anyFileCausesBlacklist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			ChangedFilesValue: []string{"server/server.go", "migrations/2018/0001_init.sql"},
+		}
+
+		actualBlacklist, reason, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.Nil(t, err)
+		assert.True(t, actualBlacklist)
+		assert.Equal(t, `PR changed file migrations/2018/0001_init.sql matches one of specified blacklist changed files: "migrations/"`, reason)
+	})
+
+	t.Run("allFilesCauseWhitelist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			ChangedFilesValue: []string{"docs/setup/install.txt", "pull/README.md"},
+		}
+
+		actualWhitelist, reason, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.Nil(t, err)
+		assert.True(t, actualWhitelist)
+		assert.Equal(t, `all PR changed files match specified whitelist changed files: "docs/, *.md"`, reason)
+	})
+
+	t.Run("nestedFileDoesntMatchSingleLevelPattern", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			ChangedFilesValue: []string{"infra/terraform/modules/main.tf"},
+		}
+
+		actualBlacklist, _, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.Nil(t, err)
+		assert.False(t, actualBlacklist)
+	})
+
+	t.Run("someFilesDontCauseWhitelist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			ChangedFilesValue: []string{"docs/install.md", "server/server.go"},
+		}
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.Nil(t, err)
+		assert.False(t, actualWhitelist)
+	})
+
+	t.Run("noFilesDontCauseWhitelist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{}
+
+		actualWhitelist, _, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.Nil(t, err)
+		assert.False(t, actualWhitelist)
+	})
+
+	t.Run("tooManyFilesFailsClosedBlacklist", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			ChangedFilesErrValue: pull.ErrTooManyChangedFiles,
+		}
+
+		actualBlacklist, _, err := IsPRBlacklisted(ctx, pc, mergeConfig.Blacklist)
+		require.NotNil(t, err)
+		assert.True(t, actualBlacklist)
+	})
+
+	t.Run("tooManyFilesIsNotWhitelisted", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			ChangedFilesErrValue: errors.Wrap(pull.ErrTooManyChangedFiles, "owner/repo#1 changes 3001 files"),
+		}
+
+		actualWhitelist, reason, err := IsPRWhitelisted(ctx, pc, mergeConfig.Whitelist)
+		require.NoError(t, err)
+		assert.False(t, actualWhitelist)
+		assert.Contains(t, reason, "more than 3000 files")
+	})
+}
+
 func TestShouldMerge(t *testing.T) {
 	mergeConfig := MergeConfig{
 		Whitelist: Signals{
@@ -303,7 +566,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.True(t, actualShouldMerge)
+		assert.True(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("partialCommentShouldntMerge", func(t *testing.T) {
@@ -314,7 +577,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("labelShouldMerge", func(t *testing.T) {
@@ -325,7 +588,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.True(t, actualShouldMerge)
+		assert.True(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("labelShouldMergeCaseInsensitive", func(t *testing.T) {
@@ -336,7 +599,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.True(t, actualShouldMerge)
+		assert.True(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("noContextShouldntMerge", func(t *testing.T) {
@@ -348,7 +611,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("noMatchingShouldntMerge", func(t *testing.T) {
@@ -357,7 +620,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("blacklistOverridesWhitelist", func(t *testing.T) {
@@ -369,7 +632,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("labelCausesBlacklist", func(t *testing.T) {
@@ -380,7 +643,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("labelCausesBlacklistCaseInsensitive", func(t *testing.T) {
@@ -391,7 +654,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("substringCausesWhitelist", func(t *testing.T) {
@@ -403,7 +666,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.True(t, actualShouldMerge)
+		assert.True(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("substringCausesBlacklist", func(t *testing.T) {
@@ -415,7 +678,10 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
+		require.NotNil(t, actualShouldMerge.Blacklist)
+		assert.Equal(t, "labels", actualShouldMerge.Blacklist.Signal)
+		assert.Equal(t, "LABEL_NOMERGE", actualShouldMerge.Blacklist.Value)
 	})
 
 	t.Run("failClosedOnLabelErr", func(t *testing.T) {
@@ -428,7 +694,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.NotNil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("failClosedOnCommentErr", func(t *testing.T) {
@@ -440,7 +706,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.NotNil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("failClosedOnRequiredStatusCheckErr", func(t *testing.T) {
@@ -452,7 +718,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.NotNil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("failClosedOnSuccessStatusCheckErr", func(t *testing.T) {
@@ -464,7 +730,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.NotNil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("allStatusChecksMet", func(t *testing.T) {
@@ -477,7 +743,7 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.True(t, actualShouldMerge)
+		assert.True(t, actualShouldMerge.Allowed)
 	})
 
 	t.Run("notAllStatusChecksMet", func(t *testing.T) {
@@ -490,6 +756,169 @@ func TestShouldMerge(t *testing.T) {
 		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
 
 		require.Nil(t, err)
-		assert.False(t, actualShouldMerge)
+		assert.False(t, actualShouldMerge.Allowed)
+		assert.Equal(t, []string{"StatusCheckB"}, actualShouldMerge.MissingStatuses)
 	})
+
+	t.Run("draftShouldntMerge", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue: []string{"LABEL_MERGE"},
+			DraftValue: true,
+		}
+
+		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
+
+		require.Nil(t, err)
+		assert.False(t, actualShouldMerge.Allowed)
+		assert.True(t, actualShouldMerge.Draft)
+		assert.Equal(t, "pull request is a draft", actualShouldMerge.Summary())
+	})
+
+	t.Run("draftShouldMergeIfAllowed", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue:    []string{"LABEL_MERGE"},
+			DraftErrValue: errors.New("failure"),
+		}
+
+		draftConfig := mergeConfig
+		draftConfig.AllowDrafts = true
+		actualShouldMerge, err := ShouldMergePR(ctx, pc, draftConfig)
+
+		require.Nil(t, err)
+		assert.True(t, actualShouldMerge.Allowed)
+	})
+
+	t.Run("failClosedOnDraftErr", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue:    []string{"LABEL_MERGE"},
+			DraftErrValue: errors.New("failure"),
+		}
+
+		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
+
+		require.NotNil(t, err)
+		assert.False(t, actualShouldMerge.Allowed)
+	})
+}
+
+func TestShouldMergeWithCheckRuns(t *testing.T) {
+	mergeConfig := MergeConfig{
+		Whitelist: Signals{
+			Labels: []string{"LABEL_MERGE"},
+		},
+	}
+
+	ctx := context.Background()
+
+	t.Run("completedCheckRunSatisfiesRequiredStatus", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue:            []string{"LABEL_MERGE"},
+			SuccessStatusesValue:  []string{"StatusCheckA"},
+			RequiredStatusesValue: []string{"StatusCheckA", "build"},
+			CheckRunsValue: []pull.CheckRun{
+				{Name: "build", Status: "completed", Conclusion: "neutral"},
+			},
+		}
+
+		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
+
+		require.Nil(t, err)
+		assert.True(t, actualShouldMerge.Allowed)
+	})
+
+	t.Run("inProgressCheckRunIsMissing", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue:            []string{"LABEL_MERGE"},
+			RequiredStatusesValue: []string{"build"},
+			CheckRunsValue: []pull.CheckRun{
+				{Name: "build", Status: "in_progress"},
+			},
+		}
+
+		actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
+
+		require.Nil(t, err)
+		assert.False(t, actualShouldMerge.Allowed)
+		assert.Equal(t, []string{"build"}, actualShouldMerge.MissingStatuses)
+	})
+
+	t.Run("conclusionsAreConfigurable", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue:            []string{"LABEL_MERGE"},
+			RequiredStatusesValue: []string{"build"},
+			CheckRunsValue: []pull.CheckRun{
+				{Name: "build", Status: "completed", Conclusion: "neutral"},
+			},
+		}
+
+		strictConfig := mergeConfig
+		strictConfig.CheckConclusions = []string{"success"}
+		actualShouldMerge, err := ShouldMergePR(ctx, pc, strictConfig)
+
+		require.Nil(t, err)
+		assert.False(t, actualShouldMerge.Allowed)
+	})
+}
+
+func TestShouldMergeWithStatusStates(t *testing.T) {
+	mergeConfig := MergeConfig{
+		Whitelist: Signals{
+			Labels: []string{"LABEL_MERGE"},
+		},
+	}
+
+	ctx := context.Background()
+
+	pc := &pulltest.MockPullContext{
+		LabelValue:            []string{"LABEL_MERGE"},
+		RequiredStatusesValue: []string{"StatusCheckA", "StatusCheckB", "StatusCheckC", "build"},
+		StatusesValue: []pull.Status{
+			{Context: "StatusCheckA", State: pull.StatusSuccess},
+			{Context: "StatusCheckB", State: pull.StatusPending},
+			{Context: "StatusCheckC", State: pull.StatusError},
+		},
+		CheckRunsValue: []pull.CheckRun{
+			{Name: "build", Status: "completed", Conclusion: "failure"},
+		},
+	}
+
+	actualShouldMerge, err := ShouldMergePR(ctx, pc, mergeConfig)
+
+	require.Nil(t, err)
+	assert.False(t, actualShouldMerge.Allowed)
+	assert.Equal(t, []string{"StatusCheckB", "StatusCheckC", "build"}, actualShouldMerge.MissingStatuses)
+	assert.Equal(t, []string{"StatusCheckB"}, actualShouldMerge.PendingStatuses)
+	assert.Equal(t, []string{"StatusCheckC", "build"}, actualShouldMerge.FailedStatuses)
+	assert.Equal(t, "StatusCheckC, build failed", actualShouldMerge.Summary())
+}
+
+func TestRequiredStatusChecks(t *testing.T) {
+	ctx := context.Background()
+
+	protected := make([]string, 1, 4)
+	protected[0] = "build"
+	pc := &pulltest.MockPullContext{
+		RequiredStatusesValue: protected,
+	}
+
+	required, err := RequiredStatusChecks(ctx, pc, MergeConfig{RequiredStatuses: []string{"lint"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"build", "lint"}, required)
+
+	required, err = RequiredStatusChecks(ctx, pc, MergeConfig{RequiredStatuses: []string{"docs"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"build", "docs"}, required)
+	assert.Equal(t, []string{"build", "", ""}, protected[:3], "configured statuses must not be written to the branch protection statuses")
+}
+
+func TestEvaluationResultSummary(t *testing.T) {
+	assert.Equal(t, "blacklisted by label do-not-merge", EvaluationResult{
+		Blacklist: &SignalMatch{Signal: "labels", Source: "label", Value: "do-not-merge"},
+	}.Summary())
+
+	assert.Equal(t, "waiting for ci/circleci: ete-tests", EvaluationResult{
+		MissingStatuses: []string{"ci/circleci: ete-tests"},
+	}.Summary())
+
+	assert.Equal(t, "ready to merge", EvaluationResult{Allowed: true}.Summary())
 }
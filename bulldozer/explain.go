@@ -0,0 +1,152 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CyberhavenInc/bulldozer/queue"
+	"github.com/CyberhavenInc/bulldozer/scheduler"
+	"github.com/CyberhavenInc/bulldozer/state"
+)
+
+// Explanation collects everything bulldozer knows about a pull request to
+// explain why it is or is not merged or updated.
+type Explanation struct {
+	// Config describes the source of the configuration, see
+	// FetchedConfig.String
+	Config string
+
+	Merge  EvaluationResult
+	Update EvaluationResult
+
+	// BehindBase is nil if it could not be determined
+	BehindBase *bool
+
+	// QueueEntry is the entry of the pull request in the queue of its base
+	// branch, if any. QueuePosition is its 1-based position among the
+	// queued entries, or 0 if it is not queued.
+	QueueEntry    *queue.Entry
+	QueuePosition int
+
+	LastError *state.PRError
+
+	// Attempts are the pending merge and update attempts of this instance
+	Attempts []scheduler.Job
+}
+
+// Markdown renders the explanation as a markdown comment.
+func (e Explanation) Markdown() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "**Configuration:** `%s`\n", e.Config)
+
+	sb.WriteString("\n#### Merge\n\n")
+	fmt.Fprintf(&sb, "**Decision:** %s\n\n", e.Merge.Summary())
+	if e.Merge.Reason != "" {
+		fmt.Fprintf(&sb, "%s\n\n", e.Merge.Reason)
+	}
+	writeSignals(&sb, e.Merge)
+	writeList(&sb, "Required statuses", e.Merge.RequiredStatuses)
+	writeList(&sb, "Missing statuses", e.Merge.MissingStatuses)
+	writeList(&sb, "Pending statuses", e.Merge.PendingStatuses)
+	writeList(&sb, "Failed statuses", e.Merge.FailedStatuses)
+	if e.Merge.RequiredApprovals > 0 {
+		fmt.Fprintf(&sb, "- Approvals: %d of %d required", len(e.Merge.ApprovedBy), e.Merge.RequiredApprovals)
+		if len(e.Merge.ApprovedBy) > 0 {
+			fmt.Fprintf(&sb, " (%s)", strings.Join(e.Merge.ApprovedBy, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	writeList(&sb, "Changes requested by", e.Merge.ChangesRequestedBy)
+	writeList(&sb, "Missing code owners", e.Merge.MissingCodeOwners)
+	writeRules(&sb, e.Merge.Rules)
+
+	sb.WriteString("\n#### Update\n\n")
+	fmt.Fprintf(&sb, "**Decision:** %s\n\n", e.Update.Reason)
+	writeSignals(&sb, e.Update)
+	switch {
+	case e.BehindBase == nil:
+		sb.WriteString("- Behind base branch: unknown\n")
+	case *e.BehindBase:
+		sb.WriteString("- Behind base branch: yes\n")
+	default:
+		sb.WriteString("- Behind base branch: no\n")
+	}
+	writeRules(&sb, e.Update.Rules)
+
+	sb.WriteString("\n#### Queue\n\n")
+	switch {
+	case e.QueueEntry == nil:
+		sb.WriteString("Not in the queue.\n")
+	case e.QueuePosition > 0:
+		fmt.Fprintf(&sb, "Position %d, %s since %s.\n", e.QueuePosition, e.QueueEntry.State, e.QueueEntry.UpdatedAt.Format(time.RFC3339))
+	default:
+		fmt.Fprintf(&sb, "%s since %s", e.QueueEntry.State, e.QueueEntry.UpdatedAt.Format(time.RFC3339))
+		if e.QueueEntry.Reason != "" {
+			fmt.Fprintf(&sb, ": %s", e.QueueEntry.Reason)
+		}
+		sb.WriteString(".\n")
+	}
+
+	if len(e.Attempts) > 0 {
+		sb.WriteString("\n#### Pending attempts\n\n")
+		for _, job := range e.Attempts {
+			fmt.Fprintf(&sb, "- %s: %d attempts so far, next at %s, gives up at %s", job.Kind, job.Attempts, job.NextAttempt.Format(time.RFC3339), job.Deadline.Format(time.RFC3339))
+			if job.LastError != "" {
+				fmt.Fprintf(&sb, ", last error: %s", job.LastError)
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	sb.WriteString("\n#### Last error\n\n")
+	if e.LastError == nil {
+		sb.WriteString("None.\n")
+	} else {
+		fmt.Fprintf(&sb, "%s failed at %s: %s\n", e.LastError.Operation, e.LastError.At.Format(time.RFC3339), e.LastError.Message)
+	}
+
+	return sb.String()
+}
+
+func writeSignals(sb *strings.Builder, result EvaluationResult) {
+	if result.Draft {
+		sb.WriteString("- Draft: yes\n")
+	}
+	if result.Command != nil {
+		fmt.Fprintf(sb, "- Command: `%s` by @%s\n", result.Command.Text, result.Command.Author)
+	}
+	if result.Blacklist != nil {
+		fmt.Fprintf(sb, "- Blacklist: %s\n", result.Blacklist.Reason)
+	}
+	if result.Whitelist != nil {
+		fmt.Fprintf(sb, "- Whitelist: %s\n", result.Whitelist.Reason)
+	}
+}
+
+func writeList(sb *strings.Builder, title string, values []string) {
+	if len(values) > 0 {
+		fmt.Fprintf(sb, "- %s: %s\n", title, strings.Join(values, ", "))
+	}
+}
+
+func writeRules(sb *strings.Builder, trace *RuleTrace) {
+	if trace != nil {
+		fmt.Fprintf(sb, "- Rules:\n\n```\n%s\n```\n", trace.String())
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/CyberhavenInc/bulldozer/queue"
+	"github.com/CyberhavenInc/bulldozer/state"
+)
+
+func TestExplanationMarkdown(t *testing.T) {
+	at := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	behind := true
+
+	t.Run("complete", func(t *testing.T) {
+		e := Explanation{
+			Config: FetchedConfig{Owner: "owner", Repo: "repo", Ref: "develop", Path: ".bulldozer.v1.yml", Version: 1}.String(),
+			Merge: EvaluationResult{
+				Reason:           "waiting for required statuses",
+				Whitelist:        newSignalMatch("whitelist", "labels", "label", "merge when ready"),
+				RequiredStatuses: []string{"ci/build", "ci/test"},
+				MissingStatuses:  []string{"ci/test"},
+				PendingStatuses:  []string{"ci/test"},
+			},
+			Update:        EvaluationResult{Reason: "updates are not configured"},
+			BehindBase:    &behind,
+			QueueEntry:    &queue.Entry{Number: 1, State: queue.StateQueued, UpdatedAt: at},
+			QueuePosition: 2,
+			LastError:     &state.PRError{Operation: "rebase", Message: "merge conflict", At: at},
+		}
+
+		md := e.Markdown()
+		assert.Contains(t, md, "**Configuration:** `owner/repo ref=develop path=.bulldozer.v1.yml version=v1`")
+		assert.Contains(t, md, "**Decision:** waiting for ci/test")
+		assert.Contains(t, md, "- Whitelist: ")
+		assert.Contains(t, md, "- Required statuses: ci/build, ci/test\n")
+		assert.Contains(t, md, "- Missing statuses: ci/test\n")
+		assert.Contains(t, md, "- Behind base branch: yes\n")
+		assert.Contains(t, md, "Position 2, queued since 2018-06-01T12:00:00Z.")
+		assert.Contains(t, md, "rebase failed at 2018-06-01T12:00:00Z: merge conflict")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		e := Explanation{
+			Config: FetchedConfig{Owner: "owner", Repo: "repo", Ref: "develop", Path: ".bulldozer.yml"}.String(),
+			Merge: EvaluationResult{
+				Reason: "rules are not satisfied",
+				Rules:  &RuleTrace{Predicate: PredicateNot, Reason: "label matched"},
+			},
+		}
+
+		md := e.Markdown()
+		assert.Contains(t, md, "version=v0")
+		assert.Contains(t, md, "- Rules:\n\n```\n")
+		assert.Contains(t, md, "- Behind base branch: unknown\n")
+		assert.Contains(t, md, "Not in the queue.")
+		assert.Contains(t, md, "#### Last error\n\nNone.\n")
+	})
+}
@@ -18,186 +18,265 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strings"
-	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 
 	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/scheduler"
 )
 
-const MaxPullRequestPollCount = 5
+type MergeOutcome string
+
+const (
+	// MergeSucceeded means the pull request was merged
+	MergeSucceeded MergeOutcome = "succeeded"
+	// MergeSkipped means no merge was attempted, for example because the
+	// pull request is closed or not mergeable
+	MergeSkipped MergeOutcome = "skipped"
+	// MergeRejected means GitHub refused the merge, for example because a
+	// required review is missing
+	MergeRejected MergeOutcome = "rejected"
+	// MergeFailed means the merge failed for an unexpected reason
+	MergeFailed MergeOutcome = "failed"
+	// MergeHeadModified means the head of the pull request changed after it
+	// was evaluated, so it must be evaluated again before it is merged
+	MergeHeadModified MergeOutcome = "head modified"
+)
+
+type MergeResult struct {
+	Outcome MergeOutcome
+	Reason  string
+	SHA     string
+	Err     error
+}
 
-func MergePR(ctx context.Context, pullCtx pull.Context, client *github.Client, mergeConfig MergeConfig) error {
+type mergeResultCallback func(MergeResult)
+
+// MergePR schedules the merge of the pull request, which is attempted until
+// GitHub knows whether it is mergeable. The merge only succeeds if the head
+// of the pull request is still headSHA, the commit that was evaluated, unless
+// headSHA is empty. onResult is called once with the outcome, unless the merge
+// is cancelled or replaced by a newer merge of the same pull request.
+func MergePR(ctx context.Context, pullCtx pull.Context, client *github.Client, sched *scheduler.Scheduler, mergeConfig MergeConfig, headSHA string, onResult mergeResultCallback) error {
 	logger := zerolog.Ctx(ctx)
 
-	mergeOpts := &github.PullRequestOptions{}
+	mergeOpts := &github.PullRequestOptions{SHA: headSHA}
 
 	base, _, err := pullCtx.Branches(ctx)
 	if err != nil {
 		logger.Error().Msg("Unable to find the base branch. Aborting.")
+		onResult(MergeResult{Outcome: MergeFailed, Reason: "unable to find the base branch", Err: err})
 		return err
 	}
 
-	mergeMethod := mergeConfig.Method
-
-	if branchMergeMethod, ok := mergeConfig.BranchMethod[base]; ok {
-		mergeMethod = branchMergeMethod
-	}
-
-	if !isValidMergeMethod(mergeMethod) {
-		mergeMethod = MergeCommit
-	}
-
+	mergeMethod := mergeConfig.ResolveMethod(base)
 	mergeOpts.MergeMethod = string(mergeMethod)
 
+	// rebases keep the original commits, so only squash and merge commits
+	// have a message
 	commitMessage := ""
-	if mergeConfig.Method == SquashAndMerge {
-		opt, ok := mergeConfig.Options[SquashAndMerge]
-		if !ok {
+	if mergeMethod == SquashAndMerge || mergeMethod == MergeCommit {
+		opt, ok := mergeConfig.ResolveOption(base, mergeMethod)
+		if !ok && mergeMethod == SquashAndMerge {
 			logger.Error().Msgf("Unable to find matching %s in merge option configuration; using default %s", SquashAndMerge, EmptyBody)
-			opt = MergeOption{Body: EmptyBody}
+			opt, ok = MergeOption{Body: EmptyBody}, true
 		}
 
-		squashAndMergeMessage, err := calculateCommitMessage(ctx, pullCtx, client, opt)
-		if err != nil {
-			return err
+		if ok {
+			commitTitle, message, err := calculateCommitMessage(ctx, pullCtx, client, opt)
+			if err != nil {
+				onResult(MergeResult{Outcome: MergeFailed, Reason: "unable to calculate commit message", Err: err})
+				return err
+			}
+			mergeOpts.CommitTitle = commitTitle
+			commitMessage = message
 		}
-		commitMessage = squashAndMergeMessage
 	}
 
-	go func(ctx context.Context) {
-		ticker := time.NewTicker(4 * time.Second)
-		defer ticker.Stop()
+	key := scheduler.Key{Owner: pullCtx.Owner(), Repo: pullCtx.Repo(), Number: pullCtx.Number()}
+	last := MergeResult{Outcome: MergeSkipped, Reason: "mergeability not known after polling"}
 
-		for i := 0; i < MaxPullRequestPollCount; i++ {
-			<-ticker.C
+	sched.Schedule(ctx, key, scheduler.KindMerge, func(ctx context.Context, attempt int) error {
+		logger := zerolog.Ctx(ctx)
 
-			pr, _, err := client.PullRequests.Get(ctx, pullCtx.Owner(), pullCtx.Repo(), pullCtx.Number())
-			if err != nil {
-				logger.Error().Err(errors.WithStack(err)).Msgf("Failed to retrieve pull request %q", pullCtx.Locator())
-				return
-			}
+		pr, _, err := client.PullRequests.Get(ctx, pullCtx.Owner(), pullCtx.Repo(), pullCtx.Number())
+		if err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msgf("Failed to retrieve pull request %q", pullCtx.Locator())
+			last = MergeResult{Outcome: MergeFailed, Reason: "failed to retrieve pull request", Err: err}
+			return err
+		}
 
-			if pr.GetState() == "closed" {
-				logger.Debug().Msg("Pull request already closed")
-				return
-			}
+		if pr.GetState() == "closed" {
+			logger.Debug().Msg("Pull request already closed")
+			last = MergeResult{Outcome: MergeSkipped, Reason: "pull request is closed"}
+			return nil
+		}
 
-			if pr.Mergeable == nil {
-				logger.Debug().Msg("Pull request mergeability not yet known")
-				continue
-			}
+		if headSHA != "" && pr.GetHead().GetSHA() != headSHA {
+			logger.Info().Msgf("Head of pull request moved from %s to %s since evaluation", headSHA, pr.GetHead().GetSHA())
+			last = MergeResult{Outcome: MergeHeadModified, Reason: fmt.Sprintf("head changed from %s to %s", shortSHA(headSHA), shortSHA(pr.GetHead().GetSHA()))}
+			return nil
+		}
 
-			if !pr.GetMergeable() {
-				logger.Debug().Msg("Pull request is not mergeable")
-				return
-			}
+		if pr.Mergeable == nil {
+			logger.Debug().Msg("Pull request mergeability not yet known")
+			last = MergeResult{Outcome: MergeSkipped, Reason: "mergeability not known after polling"}
+			return scheduler.Retry(errors.New("mergeability not yet known"))
+		}
 
-			// Try a merge, a 405 is expected if required reviews are not satisfied
-			logger.Info().Msgf("Attempting to merge pull request with method %s", mergeOpts.MergeMethod)
-			result, _, err := client.PullRequests.Merge(ctx, pullCtx.Owner(), pullCtx.Repo(), pullCtx.Number(), commitMessage, mergeOpts)
-			if err != nil {
-				gerr, ok := err.(*github.ErrorResponse)
-				if !ok {
-					logger.Error().Err(errors.WithStack(err)).Msg("Merge failed unexpectedly")
-					continue
-				}
+		if !pr.GetMergeable() {
+			logger.Debug().Msg("Pull request is not mergeable")
+			last = MergeResult{Outcome: MergeSkipped, Reason: "pull request is not mergeable"}
+			return nil
+		}
 
-				switch gerr.Response.StatusCode {
-				case http.StatusMethodNotAllowed:
-					logger.Info().Msgf("Merge rejected due to unsatisfied condition %q", gerr.Message)
-					return
-				case http.StatusConflict:
-					logger.Info().Msgf("Merge rejected due to being invalid %q", gerr.Message)
-					return
-				default:
-					logger.Error().Err(errors.WithStack(err)).Msgf("Merge failed unexpectedly %q", gerr.Message)
-					continue
-				}
+		// Try a merge, a 405 is expected if required reviews are not satisfied
+		logger.Info().Msgf("Attempting to merge pull request with method %s", mergeOpts.MergeMethod)
+		result, _, err := client.PullRequests.Merge(ctx, pullCtx.Owner(), pullCtx.Repo(), pullCtx.Number(), commitMessage, mergeOpts)
+		if err != nil {
+			gerr, ok := err.(*github.ErrorResponse)
+			if !ok {
+				logger.Error().Err(errors.WithStack(err)).Msg("Merge failed unexpectedly")
+				last = MergeResult{Outcome: MergeFailed, Reason: "merge failed unexpectedly", Err: err}
+				return scheduler.Retry(err)
 			}
 
-			logger.Info().Msgf("Successfully merged pull request for sha %s with message %q", result.GetSHA(), result.GetMessage())
-
-			// Delete ref if owner of BASE and HEAD match
-			// otherwise, its from a fork that we cannot delete
-			if pr.GetBase().GetUser().GetLogin() == pr.GetHead().GetUser().GetLogin() {
-				if mergeConfig.DeleteAfterMerge {
-					ref := fmt.Sprintf("refs/heads/%s", pr.Head.GetRef())
-
-					// check other open PRs to make sure that nothing is trying to merge into the ref we're about to delete
-					prs, err := pull.ListOpenPullRequestsForRef(ctx, client, pullCtx.Owner(), pullCtx.Repo(), ref, false)
-					if err != nil {
-						logger.Error().Err(errors.WithStack(err)).Msgf("Unable to list open prs against ref %s to compare delete request", ref)
-						return
-					}
-
-					if len(prs) > 0 {
-						logger.Info().Msgf("Unable to delete ref %s after merging %q because there are open PRs against this ref", ref, pullCtx.Locator())
-						return
-					}
-
-					logger.Debug().Msgf("Attempting to delete ref %s", ref)
-					_, err = client.Git.DeleteRef(ctx, pullCtx.Owner(), pullCtx.Repo(), ref)
-					if err != nil {
-						logger.Error().Err(errors.WithStack(err)).Msgf("Failed to delete ref %s on %q", pr.Head.GetRef(), pullCtx.Locator())
-						return
-					}
-
-					logger.Info().Msgf("Successfully deleted ref %s on %q", pr.Head.GetRef(), pullCtx.Locator())
+			switch gerr.Response.StatusCode {
+			case http.StatusMethodNotAllowed:
+				logger.Info().Msgf("Merge rejected due to unsatisfied condition %q", gerr.Message)
+				last = MergeResult{Outcome: MergeRejected, Reason: gerr.Message}
+				return nil
+			case http.StatusConflict:
+				if isHeadModified(gerr, headSHA) {
+					logger.Info().Msgf("Merge rejected since the head is no longer %s: %q", headSHA, gerr.Message)
+					last = MergeResult{Outcome: MergeHeadModified, Reason: gerr.Message}
+					return nil
 				}
-			} else {
-				logger.Debug().Msg("Pull Request is from a fork, not deleting")
+				logger.Info().Msgf("Merge rejected due to being invalid %q", gerr.Message)
+				last = MergeResult{Outcome: MergeRejected, Reason: gerr.Message}
+				return nil
+			default:
+				logger.Error().Err(errors.WithStack(err)).Msgf("Merge failed unexpectedly %q", gerr.Message)
+				last = MergeResult{Outcome: MergeFailed, Reason: gerr.Message, Err: err}
+				return err
 			}
+		}
 
+		logger.Info().Msgf("Successfully merged pull request for sha %s with message %q", result.GetSHA(), result.GetMessage())
+		last = MergeResult{Outcome: MergeSucceeded, SHA: result.GetSHA()}
+
+		// Delete ref if owner of BASE and HEAD match
+		// otherwise, its from a fork that we cannot delete
+		if pr.GetBase().GetUser().GetLogin() == pr.GetHead().GetUser().GetLogin() {
+			if mergeConfig.DeleteAfterMerge {
+				deleteHeadRef(ctx, pullCtx, client, pr)
+			}
+		} else {
+			logger.Debug().Msg("Pull Request is from a fork, not deleting")
+		}
+		return nil
+	}, func(err error) {
+		// a cancelled merge was replaced or is no longer wanted
+		if err == scheduler.ErrCancelled {
+			logger.Debug().Msgf("Merge of %q was cancelled", pullCtx.Locator())
 			return
 		}
-	}(zerolog.Ctx(ctx).WithContext(context.Background()))
+		onResult(last)
+	})
 
 	return nil
 }
 
+// deleteHeadRef deletes the head branch of a merged pull request unless other
+// open pull requests target it. Failures are logged.
+func deleteHeadRef(ctx context.Context, pullCtx pull.Context, client *github.Client, pr *github.PullRequest) {
+	logger := zerolog.Ctx(ctx)
+	ref := fmt.Sprintf("refs/heads/%s", pr.Head.GetRef())
+
+	// check other open PRs to make sure that nothing is trying to merge into the ref we're about to delete
+	prs, err := pull.ListOpenPullRequestsForRef(ctx, client, pullCtx.Owner(), pullCtx.Repo(), ref, false)
+	if err != nil {
+		logger.Error().Err(errors.WithStack(err)).Msgf("Unable to list open prs against ref %s to compare delete request", ref)
+		return
+	}
+
+	if len(prs) > 0 {
+		logger.Info().Msgf("Unable to delete ref %s after merging %q because there are open PRs against this ref", ref, pullCtx.Locator())
+		return
+	}
+
+	logger.Debug().Msgf("Attempting to delete ref %s", ref)
+	_, err = client.Git.DeleteRef(ctx, pullCtx.Owner(), pullCtx.Repo(), ref)
+	if err != nil {
+		logger.Error().Err(errors.WithStack(err)).Msgf("Failed to delete ref %s on %q", pr.Head.GetRef(), pullCtx.Locator())
+		return
+	}
+
+	logger.Info().Msgf("Successfully deleted ref %s on %q", pr.Head.GetRef(), pullCtx.Locator())
+}
+
 func isValidMergeMethod(input MergeMethod) bool {
 	return input == SquashAndMerge || input == RebaseAndMerge || input == MergeCommit
 }
 
-func calculateCommitMessage(ctx context.Context, pullCtx pull.Context, client *github.Client, option MergeOption) (string, error) {
+// calculateCommitMessage returns the title and the message of a squash or
+// merge commit. An empty title leaves the choice to GitHub.
+func calculateCommitMessage(ctx context.Context, pullCtx pull.Context, client *github.Client, option MergeOption) (string, string, error) {
+	var data *CommitMessageData
+	if option.Title != "" || option.Body == TemplateBody || option.CoAuthoredBy || option.ReviewedBy {
+		d, err := newCommitMessageData(ctx, pullCtx, client, option)
+		if err != nil {
+			return "", "", errors.Wrap(err, "failed to collect commit template data")
+		}
+		data = &d
+	}
+
+	commitTitle := ""
+	if option.Title != "" {
+		title, err := renderCommitTitle(option.Title, *data)
+		if err != nil {
+			return "", "", err
+		}
+		commitTitle = title
+	}
+
 	commitMessage := ""
 	switch option.Body {
 	case PullRequestBody:
 		body, err := pullCtx.Body(ctx)
 		if err != nil {
-			return "", errors.Wrap(err, "failed to determine pull request body")
+			return "", "", errors.Wrap(err, "failed to determine pull request body")
 		}
 
-		commitMessage = body
-		if option.MessageDelimiter != "" {
-			var quotedDelimiter = regexp.QuoteMeta(option.MessageDelimiter)
-			var rString = fmt.Sprintf(`(?sm:(%s\s*)^(.*)$(\s*%s))`, quotedDelimiter, quotedDelimiter)
-			matcher, err := regexp.Compile(rString)
-			if err != nil {
-				return "", errors.Wrap(err, "failed to compile message delimiter regex")
-			}
-
-			if m := matcher.FindStringSubmatch(body); len(m) == 4 {
-				commitMessage = m[2]
-			}
+		commitMessage, err = delimitedBody(body, option.MessageDelimiter)
+		if err != nil {
+			return "", "", err
 		}
 	case SummarizeCommits:
 		summarizedMessages, err := summarizeCommitMessages(ctx, pullCtx, client)
 		if err != nil {
-			return "", errors.Wrap(err, "failed to collect pull request commit messages")
+			return "", "", errors.Wrap(err, "failed to collect pull request commit messages")
 		}
 
 		commitMessage = summarizedMessages
+	case TemplateBody:
+		message, err := renderCommitTemplate("message", option.Template, *data)
+		if err != nil {
+			return "", "", err
+		}
+		commitMessage = message
 	case EmptyBody:
 	default:
 	}
 
-	return commitMessage, nil
+	if option.CoAuthoredBy || option.ReviewedBy {
+		commitMessage = appendTrailers(commitMessage, commitTrailers(option, *data))
+	}
+
+	return commitTitle, commitMessage, nil
 }
 
 func summarizeCommitMessages(ctx context.Context, pullCtx pull.Context, client *github.Client) (string, error) {
@@ -235,3 +314,17 @@ func allCommits(ctx context.Context, pullCtx pull.Context, client *github.Client
 
 	return repositoryCommits, nil
 }
+
+// isHeadModified returns true if a merge was refused because the head of the
+// pull request is not the expected SHA. GitHub answers a merge pinned to a
+// SHA with 409 Conflict only if the head moved; merge conflicts are 405.
+func isHeadModified(gerr *github.ErrorResponse, headSHA string) bool {
+	return headSHA != "" && gerr.Response.StatusCode == http.StatusConflict
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
+)
+
+func TestIsHeadModified(t *testing.T) {
+	response := func(status int, message string) *github.ErrorResponse {
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: status}, Message: message}
+	}
+
+	assert.True(t, isHeadModified(response(http.StatusConflict, "Head branch was modified. Review and try the merge again."), "abc123"))
+	assert.True(t, isHeadModified(response(http.StatusConflict, "Der Head-Branch wurde geändert"), "abc123"))
+	assert.False(t, isHeadModified(response(http.StatusConflict, "Head branch was modified"), ""))
+	assert.False(t, isHeadModified(response(http.StatusMethodNotAllowed, "Pull Request is not mergeable"), "abc123"))
+}
+
+func TestShouldMergePRRecordsHeadSHA(t *testing.T) {
+	pc := &pulltest.MockPullContext{
+		HeadSHAValue: "0123456789abcdef",
+		LabelValue:   []string{"merge when ready"},
+	}
+
+	result, err := ShouldMergePR(context.Background(), pc, MergeConfig{Whitelist: Signals{Labels: []string{"merge when ready"}}})
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, "0123456789abcdef", result.HeadSHA)
+}
+
+func TestMergeConfigResolve(t *testing.T) {
+	config := MergeConfig{
+		Method:       SquashAndMerge,
+		BranchMethod: map[string]MergeMethod{"master": MergeCommit, "release": "fast-forward"},
+		Options: map[MergeMethod]MergeOption{
+			SquashAndMerge: {Body: SummarizeCommits},
+			MergeCommit:    {Body: PullRequestBody},
+		},
+		BranchOptions: map[string]map[MergeMethod]MergeOption{
+			"master": {MergeCommit: {Body: TemplateBody, Template: "{{.Title}}"}},
+		},
+	}
+
+	assert.Equal(t, SquashAndMerge, config.ResolveMethod("develop"))
+	assert.Equal(t, MergeCommit, config.ResolveMethod("master"))
+	assert.Equal(t, MergeCommit, config.ResolveMethod("release"), "invalid methods must resolve to merge commits")
+
+	option, ok := config.ResolveOption("develop", SquashAndMerge)
+	require.True(t, ok)
+	assert.Equal(t, SummarizeCommits, option.Body)
+
+	option, ok = config.ResolveOption("master", MergeCommit)
+	require.True(t, ok)
+	assert.Equal(t, TemplateBody, option.Body, "branch options must replace the options of the method")
+
+	option, ok = config.ResolveOption("master", SquashAndMerge)
+	require.True(t, ok)
+	assert.Equal(t, SummarizeCommits, option.Body)
+
+	_, ok = config.ResolveOption("develop", RebaseAndMerge)
+	assert.False(t, ok)
+}
+
+func TestUnmarshalConfigValidatesBranchOptions(t *testing.T) {
+	cf := NewConfigFetcher(".bulldozer.v1.yml", nil)
+
+	_, err := cf.unmarshalConfig([]byte(`
+version: 1
+merge:
+  method: merge
+  branch_options:
+    master:
+      merge:
+        body: template
+        template: "{{.Title}} (#{{.Number}})"
+`))
+	assert.NoError(t, err)
+
+	_, err = cf.unmarshalConfig([]byte(`
+version: 1
+merge:
+  method: merge
+  branch_options:
+    master:
+      merge:
+        body: template
+`))
+	assert.Error(t, err)
+}
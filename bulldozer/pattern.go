@@ -0,0 +1,192 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Pattern is a glob or regular expression used to match signals like labels,
+// titles, comments and bodies. Both kinds must match the whole value.
+//
+// A pattern enclosed in slashes, like "/\[WIP\].*/", is a regular expression
+// in which "." also matches line breaks. Any other pattern is a glob, where
+// "*" matches any sequence of characters and "?" matches any single
+// character. Signals are not paths, so both also match "/"; file paths are
+// matched with PathPattern.
+type Pattern struct {
+	source string
+	re     *regexp.Regexp
+	fold   *regexp.Regexp
+}
+
+func NewPattern(source string) (Pattern, error) {
+	expr := "(?s)^" + textGlobExpr(source) + "$"
+	if len(source) >= 2 && strings.HasPrefix(source, "/") && strings.HasSuffix(source, "/") {
+		expr = "(?s)^(?:" + source[1:len(source)-1] + ")$"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Pattern{}, errors.Wrapf(err, "invalid pattern %q", source)
+	}
+
+	return Pattern{
+		source: source,
+		re:     re,
+		fold:   regexp.MustCompile("(?i)" + expr),
+	}, nil
+}
+
+func (p Pattern) String() string {
+	return p.source
+}
+
+// Matches returns true if the pattern matches the value.
+func (p Pattern) Matches(value string) bool {
+	return p.re != nil && p.re.MatchString(value)
+}
+
+// MatchesFold is like Matches, but ignores case.
+func (p Pattern) MatchesFold(value string) bool {
+	return p.fold != nil && p.fold.MatchString(value)
+}
+
+func (p *Pattern) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var source string
+	if err := unmarshal(&source); err != nil {
+		return err
+	}
+
+	pattern, err := NewPattern(source)
+	if err != nil {
+		return err
+	}
+	*p = pattern
+	return nil
+}
+
+func (p Pattern) MarshalText() ([]byte, error) {
+	return []byte(p.source), nil
+}
+
+// PathPattern is a file path pattern with the gitignore-like syntax of
+// CODEOWNERS files. Globs work like in Pattern. A pattern containing a slash
+// other than a trailing one is relative to the repository root, while other
+// patterns match at any depth. A pattern matching a directory matches all
+// files inside it, except that a trailing "/*" only matches the files
+// directly inside the directory.
+type PathPattern struct {
+	source string
+	re     *regexp.Regexp
+}
+
+func NewPathPattern(source string) PathPattern {
+	trimmed := strings.TrimSuffix(source, "/")
+	anchored := strings.Contains(trimmed, "/")
+	trimmed = strings.TrimPrefix(trimmed, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+
+	b.WriteString(globExpr(trimmed))
+
+	switch {
+	case strings.HasSuffix(source, "/"):
+		b.WriteString("/.*$")
+	case strings.HasSuffix(source, "/*"):
+		b.WriteString("$")
+	default:
+		b.WriteString("(?:/.*)?$")
+	}
+
+	return PathPattern{
+		source: source,
+		re:     regexp.MustCompile(b.String()),
+	}
+}
+
+func (p PathPattern) String() string {
+	return p.source
+}
+
+// Matches returns true if the pattern matches the path.
+func (p PathPattern) Matches(path string) bool {
+	return p.re != nil && p.re.MatchString(path)
+}
+
+func (p *PathPattern) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var source string
+	if err := unmarshal(&source); err != nil {
+		return err
+	}
+
+	*p = NewPathPattern(source)
+	return nil
+}
+
+func (p PathPattern) MarshalText() ([]byte, error) {
+	return []byte(p.source), nil
+}
+
+// textGlobExpr converts a glob to an unanchored regular expression in which
+// "*" and "?" match any characters.
+func textGlobExpr(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// globExpr converts a path glob to an unanchored regular expression. "*" and
+// "?" do not match "/", "**" matches across directories, and "**/" also
+// matches no directory at all.
+func globExpr(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			if i+2 < len(glob) && glob[i+2] == '/' {
+				b.WriteString("(?:.*/)?")
+				i += 2
+			} else {
+				b.WriteString(".*")
+				i++
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
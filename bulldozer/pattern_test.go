@@ -0,0 +1,102 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
+)
+
+func TestPattern(t *testing.T) {
+	glob, err := NewPattern("merge/*")
+	require.NoError(t, err)
+	assert.True(t, glob.Matches("merge/squash"))
+	assert.False(t, glob.Matches("automerge/squash"))
+	assert.False(t, glob.Matches("Merge/Squash"))
+	assert.True(t, glob.MatchesFold("Merge/Squash"))
+
+	title, err := NewPattern("*WIP*")
+	require.NoError(t, err)
+	assert.True(t, title.Matches("feat/foo WIP"), "* must match / in titles")
+	assert.True(t, title.Matches("WIP: fix/bar"))
+
+	single, err := NewPattern("v?.0")
+	require.NoError(t, err)
+	assert.True(t, single.Matches("v/.0"))
+	assert.False(t, single.Matches("v10.0"))
+
+	body, err := NewPattern("*==MERGE_WHEN_READY==*")
+	require.NoError(t, err)
+	assert.True(t, body.Matches("Fixes #12.\n\n==MERGE_WHEN_READY==\n"))
+
+	re, err := NewPattern(`/\[WIP\].*/`)
+	require.NoError(t, err)
+	assert.True(t, re.Matches("[WIP] add feature"))
+	assert.False(t, re.Matches("add feature [WIP]"))
+
+	multiline, err := NewPattern(`/.*==MERGE_WHEN_READY==.*/`)
+	require.NoError(t, err)
+	assert.True(t, multiline.Matches("Fixes #12.\n\n==MERGE_WHEN_READY==\n"), ". must match line breaks in bodies")
+
+	anchored, err := NewPattern(`/\[WIP\]|\[DRAFT\]/`)
+	require.NoError(t, err)
+	assert.True(t, anchored.Matches("[DRAFT]"))
+	assert.False(t, anchored.Matches("[WIP] add feature"), "regular expressions must match the whole value")
+
+	_, err = NewPattern("/[/")
+	assert.Error(t, err)
+}
+
+func TestPatternConfig(t *testing.T) {
+	cf := NewConfigFetcher("", nil)
+
+	config, err := cf.unmarshalConfig([]byte(`
+version: 1
+merge:
+  blacklist:
+    title_patterns: ["/\\[WIP\\].*/"]
+  whitelist:
+    label_patterns: ["merge/*"]
+`))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	pc := &pulltest.MockPullContext{
+		TitleValue: "[WIP] not done yet",
+		LabelValue: []string{"Merge/Squash"},
+	}
+
+	blacklisted, reason, err := IsPRBlacklisted(ctx, pc, config.Merge.Blacklist)
+	require.NoError(t, err)
+	assert.True(t, blacklisted)
+	assert.Equal(t, `PR title matches one of specified blacklist title patterns: "/\\[WIP\\].*/"`, reason)
+
+	whitelisted, _, err := IsPRWhitelisted(ctx, pc, config.Merge.Whitelist)
+	require.NoError(t, err)
+	assert.True(t, whitelisted)
+
+	_, err = cf.unmarshalConfig([]byte(`
+version: 1
+merge:
+  whitelist:
+    comment_patterns: ["/(/"]
+`))
+	assert.Error(t, err, "invalid patterns must be rejected when the configuration is loaded")
+}
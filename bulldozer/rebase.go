@@ -3,28 +3,36 @@ package bulldozer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
-	"sync/atomic"
+	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/nu7hatch/gouuid"
+	"github.com/rs/zerolog"
+
+	"github.com/CyberhavenInc/bulldozer/lock"
 )
 
 const (
 	refsPrefix   = "refs/"
 	branchPrefix = "heads/"
-
-	stateUnlocked uint32 = iota
-	stateLocked
 )
 
-var prLock = stateUnlocked
+// RebaseLockTimeout is the maximum time a rebase holds the lock for its base
+// branch. The rebase is cancelled if it does not complete within this time.
+const RebaseLockTimeout = 10 * time.Minute
+
+// tmpRefDeleteTimeout bounds the deletion of the temporary ref of a rebase,
+// which runs even if the rebase ran out of time.
+const tmpRefDeleteTimeout = 30 * time.Second
 
 type withTmpRefFn func(tmpRef *string) error
 
 type RebaseHandler struct {
 	ctx    context.Context
 	client *github.Client
+	locks  lock.Locker
 	owner  string
 	repo   string
 }
@@ -48,8 +56,16 @@ func (h *RebaseHandler) withTmpRef(headSHA string, function withTmpRefFn) error
 		return err
 	}
 
-	// Always delete tmp ref
-	defer h.client.Git.DeleteRef(h.ctx, h.owner, h.repo, tmpRef.GetRef())
+	// Always delete tmp ref, even if the lease of an interlocked rebase expired
+	defer func() {
+		logger := zerolog.Ctx(h.ctx)
+		ctx, cancel := context.WithTimeout(logger.WithContext(context.Background()), tmpRefDeleteTimeout)
+		defer cancel()
+
+		if _, err := h.client.Git.DeleteRef(ctx, h.owner, h.repo, tmpRef.GetRef()); err != nil {
+			logger.Error().Err(err).Msgf("Failed to delete temporary ref %s", tmpRef.GetRef())
+		}
+	}()
 
 	return function(tmpRef.Ref)
 }
@@ -178,11 +194,30 @@ func (h *RebaseHandler) rebase(pr *github.PullRequest) error {
 	})
 }
 
-func (h *RebaseHandler) interlockedRebase(pr *github.PullRequest) (bool, error) {
-	if !atomic.CompareAndSwapUint32(&prLock, stateUnlocked, stateLocked) {
-		return true, nil
+// interlockedRebase rebases the pull request while holding the lock for its
+// base branch. If another rebase holds the lock, it returns the lease of the
+// holder and true without rebasing.
+func (h *RebaseHandler) interlockedRebase(pr *github.PullRequest) (lock.Lease, bool, error) {
+	key := lock.Key{Owner: h.owner, Repo: h.repo, Branch: pr.GetBase().GetRef()}
+	holder := fmt.Sprintf("%s/%s#%d", h.owner, h.repo, pr.GetNumber())
+
+	lease, acquired, err := h.locks.Acquire(h.ctx, key, holder, RebaseLockTimeout)
+	if err != nil {
+		return lock.Lease{}, false, err
+	}
+	if !acquired {
+		return lease, true, nil
 	}
-	defer atomic.StoreUint32(&prLock, stateUnlocked)
+	defer func() {
+		if err := h.locks.Release(h.ctx, lease); err != nil {
+			zerolog.Ctx(h.ctx).Error().Err(err).Msgf("Failed to release lock %s held by %s, it expires at %s", lease.Key, lease.Holder, lease.ExpiresAt.Format(time.RFC3339))
+		}
+	}()
+
+	ctx, cancel := context.WithDeadline(h.ctx, lease.ExpiresAt)
+	defer cancel()
 
-	return false, h.rebase(pr)
+	locked := *h
+	locked.ctx = ctx
+	return lease, false, locked.rebase(pr)
 }
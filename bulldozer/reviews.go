@@ -0,0 +1,112 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+)
+
+// reviewState is the outcome of evaluating the reviews of a pull request.
+type reviewState struct {
+	required         int
+	approvedBy       []string
+	changesRequested []string
+	missingOwners    []string
+	// unsatisfied describes why the reviews do not allow a merge, or is
+	// empty if they do
+	unsatisfied string
+}
+
+// evaluateReviews checks the reviews of a pull request against the branch
+// protection requirements and the review configuration. Only the latest
+// approving, rejecting, or dismissing review of each reviewer counts.
+func evaluateReviews(ctx context.Context, pullCtx pull.Context, config ReviewConfig) (reviewState, error) {
+	var state reviewState
+
+	requirements, err := pullCtx.ReviewRequirements(ctx)
+	if err != nil {
+		return state, errors.Wrap(err, "failed to determine review requirements")
+	}
+
+	state.required = requirements.RequiredApprovals
+	if config.MinApprovals > state.required {
+		state.required = config.MinApprovals
+	}
+	requireOwners := config.RequireCodeOwners || requirements.RequireCodeOwnerReviews
+	if state.required == 0 && !config.RequireApprovalOnLatestCommit && !requireOwners {
+		return state, nil
+	}
+
+	reviews, err := pullCtx.Reviews(ctx)
+	if err != nil {
+		return state, errors.Wrap(err, "failed to list reviews")
+	}
+
+	headSHA := pullCtx.HeadSHA()
+	ignoreStale := config.IgnoreStaleApprovals || requirements.DismissStaleReviews
+
+	latest := make(map[string]pull.Review)
+	for _, r := range reviews {
+		if r.State == pull.ReviewCommented {
+			continue
+		}
+		latest[r.Author] = r
+	}
+
+	approvedLatest := false
+	for author, r := range latest {
+		switch r.State {
+		case pull.ReviewApproved:
+			if ignoreStale && r.CommitID != headSHA {
+				continue
+			}
+			state.approvedBy = append(state.approvedBy, author)
+			if r.CommitID == headSHA {
+				approvedLatest = true
+			}
+		case pull.ReviewChangesRequested:
+			state.changesRequested = append(state.changesRequested, author)
+		}
+	}
+	sort.Strings(state.approvedBy)
+	sort.Strings(state.changesRequested)
+
+	if requireOwners {
+		state.missingOwners, err = missingCodeOwners(ctx, pullCtx, state.approvedBy)
+		if err != nil {
+			return state, err
+		}
+	}
+
+	switch {
+	case len(state.changesRequested) > 0:
+		state.unsatisfied = fmt.Sprintf("changes requested by %s", strings.Join(state.changesRequested, ", "))
+	case len(state.approvedBy) < state.required:
+		state.unsatisfied = fmt.Sprintf("%d of %d required approvals", len(state.approvedBy), state.required)
+	case config.RequireApprovalOnLatestCommit && !approvedLatest:
+		state.unsatisfied = "no approval of the latest commit"
+	case len(state.missingOwners) > 0:
+		state.unsatisfied = fmt.Sprintf("missing approval from code owners %s", strings.Join(state.missingOwners, ", "))
+	}
+
+	return state, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
+)
+
+func TestEvaluateReviews(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("noRequirements", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			ReviewsErrValue: errors.New("reviews must not be listed"),
+		}
+
+		state, err := evaluateReviews(ctx, pc, ReviewConfig{})
+		require.NoError(t, err)
+		assert.Empty(t, state.unsatisfied)
+	})
+
+	t.Run("latestReviewOfEachReviewerCounts", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			HeadSHAValue:            "head",
+			ReviewRequirementsValue: pull.ReviewRequirements{RequiredApprovals: 2},
+			ReviewsValue: []pull.Review{
+				{Author: "alice", State: pull.ReviewApproved, CommitID: "old"},
+				{Author: "bob", State: pull.ReviewApproved, CommitID: "old"},
+				{Author: "bob", State: pull.ReviewDismissed, CommitID: "old"},
+				{Author: "alice", State: pull.ReviewCommented, CommitID: "head"},
+			},
+		}
+
+		state, err := evaluateReviews(ctx, pc, ReviewConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice"}, state.approvedBy)
+		assert.Equal(t, "1 of 2 required approvals", state.unsatisfied)
+	})
+
+	t.Run("changesRequestedBlocks", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			HeadSHAValue: "head",
+			ReviewsValue: []pull.Review{
+				{Author: "alice", State: pull.ReviewApproved, CommitID: "head"},
+				{Author: "bob", State: pull.ReviewChangesRequested, CommitID: "head"},
+			},
+		}
+
+		state, err := evaluateReviews(ctx, pc, ReviewConfig{MinApprovals: 1})
+		require.NoError(t, err)
+		assert.Equal(t, "changes requested by bob", state.unsatisfied)
+	})
+
+	t.Run("staleApprovals", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			HeadSHAValue: "head",
+			ReviewsValue: []pull.Review{
+				{Author: "alice", State: pull.ReviewApproved, CommitID: "old"},
+			},
+		}
+
+		state, err := evaluateReviews(ctx, pc, ReviewConfig{MinApprovals: 1})
+		require.NoError(t, err)
+		assert.Empty(t, state.unsatisfied)
+
+		state, err = evaluateReviews(ctx, pc, ReviewConfig{MinApprovals: 1, RequireApprovalOnLatestCommit: true})
+		require.NoError(t, err)
+		assert.Equal(t, "no approval of the latest commit", state.unsatisfied)
+
+		state, err = evaluateReviews(ctx, pc, ReviewConfig{MinApprovals: 1, IgnoreStaleApprovals: true})
+		require.NoError(t, err)
+		assert.Equal(t, "0 of 1 required approvals", state.unsatisfied)
+
+		pc.ReviewRequirementsValue = pull.ReviewRequirements{RequiredApprovals: 1, DismissStaleReviews: true}
+		state, err = evaluateReviews(ctx, pc, ReviewConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, "0 of 1 required approvals", state.unsatisfied)
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+)
+
+// Rule is a boolean expression over signals. A rule passes if all of its
+// parts pass:
+//
+//   - all_of passes if every rule in it passes
+//   - any_of passes if at least one rule in it passes
+//   - not passes if the rule in it does not pass
+//   - the signals written directly in the rule pass if any of them matches,
+//     with the same semantics as a whitelist
+//
+// A rule without any parts always passes.
+type Rule struct {
+	AllOf []Rule `yaml:"all_of"`
+	AnyOf []Rule `yaml:"any_of"`
+	Not   *Rule  `yaml:"not"`
+
+	Signals `yaml:",inline"`
+}
+
+// Predicates of a RuleTrace
+const (
+	PredicateRule    = "rule"
+	PredicateAllOf   = "all_of"
+	PredicateAnyOf   = "any_of"
+	PredicateNot     = "not"
+	PredicateSignals = "signals"
+)
+
+// RuleTrace records how a rule was evaluated.
+type RuleTrace struct {
+	// Predicate is one of the Predicate constants. A rule with more than
+	// one part is traced as PredicateRule with a child for each part.
+	Predicate string `json:"predicate"`
+	Passed    bool   `json:"passed"`
+	// Reason describes the outcome of a PredicateSignals predicate
+	Reason string `json:"reason,omitempty"`
+	// Children are the evaluated sub-predicates. Evaluation stops at the
+	// first predicate that decides the outcome, so later ones are omitted.
+	Children []RuleTrace `json:"children,omitempty"`
+}
+
+// String renders the trace as an indented tree.
+func (t RuleTrace) String() string {
+	var sb strings.Builder
+	t.write(&sb, 0)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (t RuleTrace) write(sb *strings.Builder, depth int) {
+	outcome := "failed"
+	if t.Passed {
+		outcome = "passed"
+	}
+
+	fmt.Fprintf(sb, "%s%s: %s", strings.Repeat("  ", depth), t.Predicate, outcome)
+	if t.Reason != "" {
+		fmt.Fprintf(sb, " (%s)", t.Reason)
+	}
+	sb.WriteString("\n")
+
+	for _, child := range t.Children {
+		child.write(sb, depth+1)
+	}
+}
+
+// EvaluateRule evaluates a rule for a pull request and returns a trace of
+// the evaluated predicates. An error means the rule could not be evaluated
+// and must be treated as failed.
+func EvaluateRule(ctx context.Context, pullCtx pull.Context, rule Rule) (RuleTrace, error) {
+	var parts []func() (RuleTrace, error)
+
+	if len(rule.AllOf) > 0 {
+		parts = append(parts, func() (RuleTrace, error) {
+			return evaluateRules(ctx, pullCtx, PredicateAllOf, rule.AllOf, false)
+		})
+	}
+	if len(rule.AnyOf) > 0 {
+		parts = append(parts, func() (RuleTrace, error) {
+			return evaluateRules(ctx, pullCtx, PredicateAnyOf, rule.AnyOf, true)
+		})
+	}
+	if rule.Not != nil {
+		parts = append(parts, func() (RuleTrace, error) {
+			child, err := EvaluateRule(ctx, pullCtx, *rule.Not)
+			trace := RuleTrace{Predicate: PredicateNot, Passed: !child.Passed, Children: []RuleTrace{child}}
+			if err != nil {
+				trace.Passed = false
+			}
+			return trace, err
+		})
+	}
+	if rule.Signals.Enabled() {
+		parts = append(parts, func() (RuleTrace, error) {
+			trace := RuleTrace{Predicate: PredicateSignals}
+			match, reason, err := matchSignals(ctx, pullCtx, rule.Signals, "rule")
+			if err != nil {
+				trace.Reason = reason
+				return trace, errors.Wrap(err, "failed to evaluate rule signals")
+			}
+			if match != nil {
+				trace.Passed = true
+				trace.Reason = match.Reason
+			} else {
+				trace.Reason = reason
+			}
+			return trace, nil
+		})
+	}
+
+	switch len(parts) {
+	case 0:
+		return RuleTrace{Predicate: PredicateRule, Passed: true}, nil
+	case 1:
+		return parts[0]()
+	}
+
+	trace := RuleTrace{Predicate: PredicateRule, Passed: true}
+	for _, part := range parts {
+		child, err := part()
+		trace.Children = append(trace.Children, child)
+		if err != nil {
+			trace.Passed = false
+			return trace, err
+		}
+		if !child.Passed {
+			trace.Passed = false
+			break
+		}
+	}
+	return trace, nil
+}
+
+// evaluateRules evaluates rules in order until one of them passes if any is
+// true, or until one of them fails otherwise.
+func evaluateRules(ctx context.Context, pullCtx pull.Context, predicate string, rules []Rule, any bool) (RuleTrace, error) {
+	trace := RuleTrace{Predicate: predicate, Passed: !any}
+	for _, rule := range rules {
+		child, err := EvaluateRule(ctx, pullCtx, rule)
+		trace.Children = append(trace.Children, child)
+		if err != nil {
+			trace.Passed = false
+			return trace, err
+		}
+		if child.Passed == any {
+			trace.Passed = any
+			break
+		}
+	}
+	return trace, nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
+)
+
+func TestEvaluateRule(t *testing.T) {
+	cf := NewConfigFetcher("", nil)
+
+	config, err := cf.unmarshalConfig([]byte(`
+version: 1
+merge:
+  rules:
+    all_of:
+      - labels: ["automerge"]
+      - authors: ["dependabot[bot]"]
+      - changed_files: ["go.mod", "go.sum"]
+      - not:
+          any_of:
+            - title_prefixes: ["[WIP]"]
+            - labels: ["do not merge"]
+`))
+	require.NoError(t, err)
+	require.NotNil(t, config.Merge.Rules)
+	rule := *config.Merge.Rules
+
+	ctx := context.Background()
+
+	t.Run("allPredicatesPass", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue:        []string{"automerge"},
+			AuthorValue:       "dependabot[bot]",
+			ChangedFilesValue: []string{"go.mod", "go.sum"},
+			TitleValue:        "Bump github.com/pkg/errors",
+		}
+
+		trace, err := EvaluateRule(ctx, pc, rule)
+		require.NoError(t, err)
+		assert.True(t, trace.Passed)
+		assert.Equal(t, PredicateAllOf, trace.Predicate)
+		require.Len(t, trace.Children, 4)
+		assert.Equal(t, `PR label matches one of specified rule labels: "automerge"`, trace.Children[0].Reason)
+		assert.Equal(t, PredicateNot, trace.Children[3].Predicate)
+		assert.False(t, trace.Children[3].Children[0].Passed)
+	})
+
+	t.Run("failingPredicateStopsEvaluation", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue:        []string{"automerge"},
+			AuthorValue:       "dependabot[bot]",
+			ChangedFilesValue: []string{"go.mod", "main.go"},
+		}
+
+		trace, err := EvaluateRule(ctx, pc, rule)
+		require.NoError(t, err)
+		assert.False(t, trace.Passed)
+		require.Len(t, trace.Children, 3)
+		assert.False(t, trace.Children[2].Passed)
+	})
+
+	t.Run("notFailsIfRulePasses", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue:        []string{"automerge"},
+			AuthorValue:       "dependabot[bot]",
+			ChangedFilesValue: []string{"go.sum"},
+			TitleValue:        "[WIP] Bump github.com/pkg/errors",
+		}
+
+		trace, err := EvaluateRule(ctx, pc, rule)
+		require.NoError(t, err)
+		assert.False(t, trace.Passed)
+		assert.Equal(t, `all_of: failed
+  signals: passed (PR label matches one of specified rule labels: "automerge")
+  signals: passed (PR author matches one of specified rule authors: "dependabot[bot]")
+  signals: passed (all PR changed files match specified rule changed files: "go.mod, go.sum")
+  not: failed
+    any_of: passed
+      signals: passed (PR title matches one of specified rule title prefixes: "[WIP]")`, trace.String())
+	})
+
+	t.Run("errorFailsClosed", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelErrValue: errors.New("failure"),
+		}
+
+		trace, err := EvaluateRule(ctx, pc, Rule{Not: &Rule{Signals: Signals{Labels: []string{"do not merge"}}}})
+		require.Error(t, err)
+		assert.False(t, trace.Passed)
+	})
+
+	t.Run("emptyRulePasses", func(t *testing.T) {
+		trace, err := EvaluateRule(ctx, &pulltest.MockPullContext{}, Rule{})
+		require.NoError(t, err)
+		assert.True(t, trace.Passed)
+	})
+
+	t.Run("multiplePartsMustAllPass", func(t *testing.T) {
+		pc := &pulltest.MockPullContext{
+			LabelValue: []string{"automerge"},
+		}
+
+		trace, err := EvaluateRule(ctx, pc, Rule{
+			Signals: Signals{Labels: []string{"automerge"}},
+			Not:     &Rule{Signals: Signals{Labels: []string{"automerge"}}},
+		})
+		require.NoError(t, err)
+		assert.False(t, trace.Passed)
+		assert.Equal(t, PredicateRule, trace.Predicate)
+		require.Len(t, trace.Children, 1)
+		assert.Equal(t, PredicateNot, trace.Children[0].Predicate)
+	})
+}
+
+func TestShouldMergeWithRules(t *testing.T) {
+	ctx := context.Background()
+	mergeConfig := MergeConfig{
+		Whitelist: Signals{Labels: []string{"automerge"}},
+		Rules: &Rule{
+			Signals: Signals{Authors: []string{"dependabot[bot]"}},
+		},
+	}
+
+	pc := &pulltest.MockPullContext{
+		LabelValue:  []string{"automerge"},
+		AuthorValue: "octocat",
+	}
+
+	result, err := ShouldMergePR(ctx, pc, mergeConfig)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	require.NotNil(t, result.Rules)
+	assert.Equal(t, "rules are not satisfied", result.Summary())
+
+	pc.AuthorValue = "dependabot[bot]"
+	result, err = ShouldMergePR(ctx, pc, mergeConfig)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,163 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	CoAuthoredByTrailer = "Co-authored-by"
+	ReviewedByTrailer   = "Reviewed-by"
+)
+
+// gitHubCommitterEmail is the committer of commits created through the GitHub
+// web interface, which is not a person to credit
+const gitHubCommitterEmail = "noreply@github.com"
+
+var (
+	trailerPattern  = regexp.MustCompile(`^([A-Za-z0-9-]+):\s*(.+)$`)
+	identityPattern = regexp.MustCompile(`^(.*?)\s*<([^<>]+)>$`)
+)
+
+// coAuthors returns a Co-authored-by value for each distinct author and
+// committer of the commits and for the co-authors named in commit messages.
+// Identities are compared by email and the pull request author is skipped,
+// since GitHub credits them as the author of the squash commit.
+func coAuthors(data CommitMessageData) []string {
+	var result []string
+	seen := map[string]bool{gitHubCommitterEmail: true}
+
+	add := func(login, name, email string) {
+		key := strings.ToLower(email)
+		if email == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		if login != "" && strings.EqualFold(login, data.Author) {
+			return
+		}
+		if name == "" {
+			name = login
+		}
+		result = append(result, fmt.Sprintf("%s <%s>", name, email))
+	}
+
+	for _, c := range data.Commits {
+		add(c.Author, c.AuthorName, c.AuthorEmail)
+		add(c.Committer, c.CommitterName, c.CommitterEmail)
+		for _, value := range trailerValues(c.Message, CoAuthoredByTrailer) {
+			if m := identityPattern.FindStringSubmatch(value); m != nil {
+				add("", m[1], m[2])
+			}
+		}
+	}
+	return result
+}
+
+// trailerValues returns the values of the trailers with the given key in the
+// last paragraph of message.
+func trailerValues(message, key string) []string {
+	var values []string
+	for _, line := range trailerBlock(message) {
+		if m := trailerPattern.FindStringSubmatch(line); m != nil && strings.EqualFold(m[1], key) {
+			values = append(values, strings.TrimSpace(m[2]))
+		}
+	}
+	return values
+}
+
+// trailerBlock returns the lines of the last paragraph of message if all of
+// them are trailers, or nil otherwise. Like git, a message that is a single
+// paragraph has no trailers.
+func trailerBlock(message string) []string {
+	message = strings.TrimRight(message, " \t\r\n")
+	idx := strings.LastIndex(message, "\n\n")
+	if idx < 0 {
+		return nil
+	}
+
+	lines := strings.Split(message[idx+2:], "\n")
+	for _, line := range lines {
+		if !trailerPattern.MatchString(strings.TrimSpace(line)) {
+			return nil
+		}
+	}
+	return lines
+}
+
+// appendTrailers adds trailers, formatted as "Key: value", to the trailer
+// block at the end of message, or starts one. Trailers the block already
+// contains are not repeated and existing trailers are kept.
+func appendTrailers(message string, trailers []string) string {
+	block := trailerBlock(message)
+
+	existing := make(map[string]bool)
+	for _, line := range block {
+		existing[strings.ToLower(strings.TrimSpace(line))] = true
+	}
+
+	var added []string
+	for _, trailer := range trailers {
+		key := strings.ToLower(trailer)
+		if !existing[key] {
+			existing[key] = true
+			added = append(added, trailer)
+		}
+	}
+	if len(added) == 0 {
+		return message
+	}
+
+	message = strings.TrimRight(message, " \t\r\n")
+	switch {
+	case message == "":
+		return strings.Join(added, "\n")
+	case block != nil:
+		return message + "\n" + strings.Join(added, "\n")
+	default:
+		return message + "\n\n" + strings.Join(added, "\n")
+	}
+}
+
+// noreplyIdentity returns a "Name <email>" identity for a GitHub user with
+// the noreply address GitHub attributes to the account, which is
+// "<id>+<login>@users.noreply.github.com", or "<login>@users.noreply.github.com"
+// if the ID is not known.
+func noreplyIdentity(login string, id int64) string {
+	if id == 0 {
+		return fmt.Sprintf("%s <%s@users.noreply.github.com>", login, login)
+	}
+	return fmt.Sprintf("%s <%d+%s@users.noreply.github.com>", login, id, login)
+}
+
+// commitTrailers returns the trailers that option adds to a squash or merge
+// commit.
+func commitTrailers(option MergeOption, data CommitMessageData) []string {
+	var trailers []string
+	if option.CoAuthoredBy {
+		for _, author := range coAuthors(data) {
+			trailers = append(trailers, fmt.Sprintf("%s: %s", CoAuthoredByTrailer, author))
+		}
+	}
+	if option.ReviewedBy {
+		for _, approver := range data.Approvers {
+			trailers = append(trailers, fmt.Sprintf("%s: %s", ReviewedByTrailer, noreplyIdentity(approver, data.ReviewerIDs[approver])))
+		}
+	}
+	return trailers
+}
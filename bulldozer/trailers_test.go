@@ -0,0 +1,74 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulldozer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitTrailers(t *testing.T) {
+	data := CommitMessageData{
+		Author: "alice",
+		Commits: []CommitData{
+			{
+				Author: "alice", AuthorName: "Alice", AuthorEmail: "alice@example.com",
+				Committer: "web-flow", CommitterName: "GitHub", CommitterEmail: "noreply@github.com",
+				Message: "Add retries\n\nCo-authored-by: Dana <dana@example.com>\nCo-authored-by: Alice <ALICE@example.com>",
+			},
+			{
+				Author: "bob", AuthorName: "Bob", AuthorEmail: "bob@example.com",
+				Committer: "carol", CommitterName: "Carol", CommitterEmail: "carol@example.com",
+				Message: "Fix tests",
+			},
+			{
+				Author: "bob", AuthorName: "Bob B.", AuthorEmail: "Bob@Example.com",
+				Committer: "bob", CommitterName: "Bob", CommitterEmail: "bob@example.com",
+				Message: "Co-authored-by: Eve <eve@example.com>",
+			},
+		},
+		Approvers:   []string{"frank", "grace"},
+		ReviewerIDs: map[string]int64{"frank": 1234},
+	}
+
+	assert.Equal(t, []string{
+		"Co-authored-by: Dana <dana@example.com>",
+		"Co-authored-by: Bob <bob@example.com>",
+		"Co-authored-by: Carol <carol@example.com>",
+		"Reviewed-by: frank <1234+frank@users.noreply.github.com>",
+		"Reviewed-by: grace <grace@users.noreply.github.com>",
+	}, commitTrailers(MergeOption{CoAuthoredBy: true, ReviewedBy: true}, data))
+
+	assert.Empty(t, commitTrailers(MergeOption{}, data))
+}
+
+func TestAppendTrailers(t *testing.T) {
+	trailers := []string{"Co-authored-by: Bob <bob@example.com>", "Reviewed-by: frank <1234+frank@users.noreply.github.com>"}
+
+	assert.Equal(t, "Co-authored-by: Bob <bob@example.com>\nReviewed-by: frank <1234+frank@users.noreply.github.com>", appendTrailers("", trailers))
+
+	assert.Equal(t, "* Add retries\n* Fix tests\n\nCo-authored-by: Bob <bob@example.com>\nReviewed-by: frank <1234+frank@users.noreply.github.com>",
+		appendTrailers("* Add retries\n* Fix tests\n", trailers))
+
+	assert.Equal(t, "Add retries\n\nSigned-off-by: Alice <alice@example.com>\nco-authored-by: bob <bob@example.com>\nReviewed-by: frank <1234+frank@users.noreply.github.com>",
+		appendTrailers("Add retries\n\nSigned-off-by: Alice <alice@example.com>\nco-authored-by: bob <bob@example.com>", trailers),
+		"existing trailers must be kept and not repeated")
+
+	assert.Equal(t, "Fix: handle nil\n\nCo-authored-by: Bob <bob@example.com>\nReviewed-by: frank <1234+frank@users.noreply.github.com>",
+		appendTrailers("Fix: handle nil", trailers), "a single paragraph is not a trailer block")
+
+	assert.Equal(t, "Message", appendTrailers("Message", nil))
+}
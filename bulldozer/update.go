@@ -16,59 +16,78 @@ package bulldozer
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 
+	"github.com/CyberhavenInc/bulldozer/lock"
 	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/scheduler"
+	"github.com/CyberhavenInc/bulldozer/state"
 )
 
-type rebaseUpdateCallback func(string)
+type UpdateOutcome string
+
+const (
+	// UpdateSucceeded means the pull request was rebased onto its base
+	UpdateSucceeded UpdateOutcome = "succeeded"
+	// UpdateNotNeeded means the pull request is already up to date
+	UpdateNotNeeded UpdateOutcome = "not_needed"
+	// UpdateSkipped means the pull request cannot be updated right now, for
+	// example because it is closed, from a fork or a recent rebase failed
+	UpdateSkipped UpdateOutcome = "skipped"
+	// UpdateDeferred means the update should be retried later because
+	// another rebase is in progress
+	UpdateDeferred UpdateOutcome = "deferred"
+	// UpdateFailed means the rebase was attempted and failed
+	UpdateFailed UpdateOutcome = "failed"
+	// UpdateCancelled means the update was cancelled before it finished,
+	// for example because the server is shutting down
+	UpdateCancelled UpdateOutcome = "cancelled"
+)
 
-const failThresholdMinutes = 60
+type UpdateResult struct {
+	Outcome UpdateOutcome
+	Reason  string
+	Err     error
+}
 
-var failedRebases map[int]time.Time
+type rebaseUpdateCallback func(UpdateResult)
 
-func RemoveFailedPR(prNumber int) {
-	if failedRebases != nil {
-		delete(failedRebases, prNumber)
-	}
-}
+const failThresholdMinutes = 60
 
-func ShouldUpdatePR(ctx context.Context, pullCtx pull.Context, updateConfig UpdateConfig) (bool, error) {
+// ShouldUpdatePR evaluates whether a pull request should be kept up to date
+// with its base branch. The returned error is also recorded in the result.
+func ShouldUpdatePR(ctx context.Context, pullCtx pull.Context, updateConfig UpdateConfig) (EvaluationResult, error) {
 	logger := zerolog.Ctx(ctx)
 
-	if !updateConfig.Blacklist.Enabled() && !updateConfig.Whitelist.Enabled() {
-		return false, nil
+	if !updateConfig.Blacklist.Enabled() && !updateConfig.Whitelist.Enabled() && updateConfig.Rules == nil {
+		return EvaluationResult{Reason: "updates are not configured"}, nil
 	}
 
-	if updateConfig.Blacklist.Enabled() {
-		blacklisted, reason, err := IsPRBlacklisted(ctx, pullCtx, updateConfig.Blacklist)
-		if err != nil {
-			return false, errors.Wrap(err, "failed to determine if pull request is blacklisted")
-		}
-		if blacklisted {
-			logger.Debug().Msgf("%s is deemed not updateable because blacklisting is enabled and %s", pullCtx.Locator(), reason)
-			return false, nil
-		}
+	result, ok := evaluateSignals(ctx, pullCtx, signalConfig{
+		Blacklist:   updateConfig.Blacklist,
+		Whitelist:   updateConfig.Whitelist,
+		Rules:       updateConfig.Rules,
+		AllowDrafts: updateConfig.AllowDrafts,
+	})
+	if result.Error != nil {
+		return result, result.Error
 	}
-
-	if updateConfig.Whitelist.Enabled() {
-		whitelisted, reason, err := IsPRWhitelisted(ctx, pullCtx, updateConfig.Whitelist)
-		if err != nil {
-			return false, errors.Wrap(err, "failed to determine if pull request is whitelisted")
-		}
-		if !whitelisted {
-			logger.Debug().Msgf("%s is deemed not updateable because whitelisting is enabled and no whitelist signal detected", pullCtx.Locator())
-			return false, nil
-		}
-
-		logger.Debug().Msgf("%s is whitelisted because whitelisting is enabled and %s", pullCtx.Locator(), reason)
+	if !ok {
+		logger.Debug().Msgf("%s is deemed not updateable because %s", pullCtx.Locator(), result.Reason)
+		return result, nil
+	}
+	if result.Whitelist != nil {
+		logger.Debug().Msgf("%s is whitelisted because whitelisting is enabled and %s", pullCtx.Locator(), result.Whitelist.Reason)
 	}
 
-	return true, nil
+	result.Allowed = true
+	result.Reason = "all update conditions are satisfied"
+	return result, nil
 }
 
 func IsPRBehindBase(ctx context.Context, client *github.Client, pullCtx pull.Context) (bool, error) {
@@ -102,83 +121,118 @@ func IsPRBehindBase(ctx context.Context, client *github.Client, pullCtx pull.Con
 	return comparison.GetBehindBy() > 0, nil
 }
 
-func UpdatePR(ctx context.Context, pullCtx pull.Context, client *github.Client, updateConfig UpdateConfig, baseRef string, onSuccess rebaseUpdateCallback) error {
+// UpdatePR schedules the rebase of the pull request onto baseRef if it is
+// behind, which is attempted until GitHub knows whether it is mergeable.
+// onResult is called once with the outcome, which is UpdateCancelled if the
+// update is cancelled or replaced by a newer update of the same pull request.
+func UpdatePR(ctx context.Context, pullCtx pull.Context, client *github.Client, sched *scheduler.Scheduler, store state.StateStore, locks lock.Locker, updateConfig UpdateConfig, baseRef string, onResult rebaseUpdateCallback) error {
 	logger := zerolog.Ctx(ctx)
 
-	//todo: should the updateConfig struct provide any other details here?
+	key := scheduler.Key{Owner: pullCtx.Owner(), Repo: pullCtx.Repo(), Number: pullCtx.Number()}
+	last := UpdateResult{Outcome: UpdateSkipped, Reason: "mergeability not known after polling"}
 
-	go func(ctx context.Context, baseRef string) {
-		ticker := time.NewTicker(2 * time.Second)
-		defer ticker.Stop()
+	sched.Schedule(ctx, key, scheduler.KindUpdate, func(ctx context.Context, attempt int) error {
+		logger := zerolog.Ctx(ctx)
 
-		for i := 0; i < MaxPullRequestPollCount; i++ {
-			<-ticker.C
+		pr, _, err := client.PullRequests.Get(ctx, pullCtx.Owner(), pullCtx.Repo(), pullCtx.Number())
+		if err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msgf("Failed to retrieve pull request %q", pullCtx.Locator())
+			last = UpdateResult{Outcome: UpdateFailed, Reason: "failed to retrieve pull request", Err: err}
+			return err
+		}
 
-			pr, _, err := client.PullRequests.Get(ctx, pullCtx.Owner(), pullCtx.Repo(), pullCtx.Number())
-			if err != nil {
-				logger.Error().Err(errors.WithStack(err)).Msgf("Failed to retrieve pull request %q", pullCtx.Locator())
-				return
-			}
+		if pr.GetState() == "closed" {
+			logger.Debug().Msg("Pull request already closed")
+			last = UpdateResult{Outcome: UpdateSkipped, Reason: "pull request is closed"}
+			return nil
+		}
 
-			if pr.GetState() == "closed" {
-				logger.Debug().Msg("Pull request already closed")
-				return
-			}
+		if pr.Mergeable == nil {
+			logger.Debug().Msg("Pull request mergeability not yet known")
+			last = UpdateResult{Outcome: UpdateSkipped, Reason: "mergeability not known after polling"}
+			return scheduler.Retry(errors.New("mergeability not yet known"))
+		}
 
-			if !pr.GetMergeable() {
-				logger.Debug().Msg("Pull request is not in mergeable state")
-				return
-			}
+		if !pr.GetMergeable() {
+			logger.Debug().Msg("Pull request is not in mergeable state")
+			last = UpdateResult{Outcome: UpdateSkipped, Reason: "pull request is not mergeable"}
+			return nil
+		}
+
+		if pr.Head.Repo.GetFork() {
+			logger.Debug().Msg("Pull request is from a fork, cannot keep it up to date with base ref")
+			last = UpdateResult{Outcome: UpdateSkipped, Reason: "pull request is from a fork"}
+			return nil
+		}
+
+		comparison, _, err := client.Repositories.CompareCommits(ctx, pullCtx.Owner(), pullCtx.Repo(), baseRef, pr.GetHead().GetSHA())
+		if err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msgf("cannot compare %s and %s for %q", baseRef, pr.GetHead().GetSHA(), pullCtx.Locator())
+			last = UpdateResult{Outcome: UpdateFailed, Reason: "failed to compare with base ref", Err: err}
+			return err
+		}
+		if comparison.GetBehindBy() == 0 {
+			logger.Debug().Msg("Pull request is not out of date, not updating")
+			last = UpdateResult{Outcome: UpdateNotNeeded}
+			return nil
+		}
 
-			if pr.Head.Repo.GetFork() {
-				logger.Debug().Msg("Pull request is from a fork, cannot keep it up to date with base ref")
-				return
+		logger.Debug().Msg("Pull request is not up to date")
+
+		prKey := state.PRKey{Owner: pullCtx.Owner(), Repo: pullCtx.Repo(), Number: pullCtx.Number()}
+
+		// Don't try to rebase if last rebase failed recently
+		now := time.Now().UTC()
+		lastFail, has, err := store.FailedRebase(ctx, prKey)
+		if err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msgf("Failed to look up previous rebase failures of %q", pullCtx.Locator())
+			last = UpdateResult{Outcome: UpdateFailed, Reason: "failed to look up previous rebase failures", Err: err}
+			return err
+		}
+		if has {
+			diff := now.Sub(lastFail)
+			if diff.Minutes() < failThresholdMinutes {
+				logger.Info().Msgf("PR rebase has failed %v ago, aborting rebase", diff)
+				last = UpdateResult{Outcome: UpdateSkipped, Reason: "previous rebase failed recently"}
+				return nil
 			}
+		}
 
-			comparison, _, err := client.Repositories.CompareCommits(ctx, pullCtx.Owner(), pullCtx.Repo(), baseRef, pr.GetHead().GetSHA())
-			if err != nil {
-				logger.Error().Err(errors.WithStack(err)).Msgf("cannot compare %s and %s for %q", baseRef, pr.GetHead().GetSHA(), pullCtx.Locator())
+		h := RebaseHandler{
+			ctx:    ctx,
+			client: client,
+			locks:  locks,
+			owner:  pullCtx.Owner(),
+			repo:   pullCtx.Repo(),
+		}
+
+		if holder, locked, err := h.interlockedRebase(pr); err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msgf("Failed to rebase pull request %q", pullCtx.Locator())
+			if err := store.SetFailedRebase(ctx, prKey, now); err != nil {
+				logger.Error().Err(errors.WithStack(err)).Msgf("Failed to record rebase failure of %q", pullCtx.Locator())
 			}
-			if comparison.GetBehindBy() > 0 {
-				logger.Debug().Msg("Pull request is not up to date")
-
-				if failedRebases == nil {
-					failedRebases = make(map[int]time.Time)
-				}
-
-				// Don't try to rebase if last rebase failed recently
-				now := time.Now().UTC()
-				if lastFail, has := failedRebases[pr.GetNumber()]; has {
-					diff := now.Sub(lastFail)
-					if diff.Minutes() < failThresholdMinutes {
-						logger.Info().Msgf("PR rebase has failed %v ago, aborting rebase", diff)
-						return
-					}
-				}
-
-				h := RebaseHandler{
-					ctx:    ctx,
-					client: client,
-					owner:  pullCtx.Owner(),
-					repo:   pullCtx.Repo(),
-				}
-
-				if locked, err := h.interlockedRebase(pr); err != nil {
-					logger.Error().Err(errors.WithStack(err)).Msgf("Failed to rebase pull request %q", pullCtx.Locator())
-					failedRebases[pr.GetNumber()] = now
-				} else if locked {
-					logger.Info().Msgf("Pull request %q is already locked, skipping", pullCtx.Locator())
-				} else {
-					onSuccess(pullCtx.Locator())
-					logger.Info().Msgf("Successfully updated pull %q request from base ref %s as rebase", pullCtx.Locator(), baseRef)
-				}
-			} else {
-				logger.Debug().Msg("Pull request is not out of date, not updating")
+			record := state.PRError{Operation: "rebase", Message: err.Error(), At: now}
+			if err := store.SetLastError(ctx, prKey, record); err != nil {
+				logger.Error().Err(errors.WithStack(err)).Msgf("Failed to record rebase error of %q", pullCtx.Locator())
 			}
-
+			last = UpdateResult{Outcome: UpdateFailed, Reason: "rebase failed", Err: err}
+		} else if locked {
+			logger.Info().Msgf("Base branch of pull request %q is locked by %s since %s, skipping", pullCtx.Locator(), holder.Holder, holder.AcquiredAt.Format(time.RFC3339))
+			last = UpdateResult{Outcome: UpdateDeferred, Reason: fmt.Sprintf("rebase of %s is in progress", holder.Holder)}
+		} else {
+			logger.Info().Msgf("Successfully updated pull %q request from base ref %s as rebase", pullCtx.Locator(), baseRef)
+			last = UpdateResult{Outcome: UpdateSucceeded}
+		}
+		return nil
+	}, func(err error) {
+		// a cancelled update was replaced or is no longer wanted
+		if err == scheduler.ErrCancelled {
+			logger.Debug().Msgf("Update of %q was cancelled", pullCtx.Locator())
+			onResult(UpdateResult{Outcome: UpdateCancelled, Reason: "update was cancelled"})
 			return
 		}
-	}(zerolog.Ctx(ctx).WithContext(context.Background()), baseRef)
+		onResult(last)
+	})
 
 	return nil
 }
@@ -58,9 +58,26 @@ func TestShouldUpdatePR(t *testing.T) {
 		require.NoError(t, err)
 		msg := fmt.Sprintf("case %d - blacklistEnabled=%t blacklisted=%t whitelistEnabled=%t whitelisted=%t -> doUpdate=%t",
 			ndx, testCase.blacklistEnabled, testCase.blacklisted, testCase.whitelistEnabled, testCase.whitelisted, testCase.expectingUpdate)
-		require.Equal(t, testCase.expectingUpdate, updating, msg)
+		require.Equal(t, testCase.expectingUpdate, updating.Allowed, msg)
 	}
 }
+
+func TestShouldUpdateDraftPR(t *testing.T) {
+	ctx := context.Background()
+	pullCtx, updateConfig := generateUpdateTestCase(false, false, true, true)
+	pullCtx.(*pulltest.MockPullContext).DraftValue = true
+
+	updating, err := ShouldUpdatePR(ctx, pullCtx, updateConfig)
+	require.NoError(t, err)
+	require.False(t, updating.Allowed)
+	require.True(t, updating.Draft)
+
+	updateConfig.AllowDrafts = true
+	updating, err = ShouldUpdatePR(ctx, pullCtx, updateConfig)
+	require.NoError(t, err)
+	require.True(t, updating.Allowed)
+}
+
 func generateUpdateTestCase(blacklistable bool, blacklisted bool, whitelistable bool, whitelisted bool) (pull.Context, UpdateConfig) {
 	updateConfig := UpdateConfig{}
 	pullCtx := pulltest.MockPullContext{}
@@ -17,6 +17,8 @@ package cmd
 import (
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -73,7 +75,22 @@ func serverCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	return errors.Wrap(s.Start(), "server terminated")
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Start()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-errs:
+		s.Stop()
+		return errors.Wrap(err, "server terminated")
+	case <-signals:
+		s.Stop()
+		return nil
+	}
 }
 
 func init() {
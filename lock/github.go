@@ -0,0 +1,223 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+const lockRefPrefix = "refs/bulldozer/lock/"
+
+// GitHubLocker is a Locker that coordinates through the Git refs API, so that
+// all bulldozer instances using the same GitHub installation agree on who
+// holds a lock.
+//
+// The lock of a branch is the ref "refs/bulldozer/lock/<branch>", or
+// "refs/bulldozer/<scope>/<branch>" for a scoped key. The ref points to a
+// commit whose message describes the lease. Creating a ref fails if it
+// already exists, which makes the first acquisition atomic. All later changes
+// are fast-forward updates to a commit whose parent is the lease they
+// replace, which fail if another instance changed the ref first: expired
+// leases are taken over with a new lease, and released leases are replaced
+// by an expired one. Refs are never deleted, because the Git refs API cannot
+// delete a ref only if it still points to a given commit.
+type GitHubLocker struct {
+	client *github.Client
+	now    func() time.Time
+}
+
+func NewGitHubLocker(client *github.Client) *GitHubLocker {
+	return &GitHubLocker{
+		client: client,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func lockRef(key Key) string {
+	if key.Scope != "" {
+		return "refs/bulldozer/" + key.Scope + "/" + key.Branch
+	}
+	return lockRefPrefix + key.Branch
+}
+
+func (l *GitHubLocker) Acquire(ctx context.Context, key Key, holder string, ttl time.Duration) (Lease, bool, error) {
+	current, found, err := l.current(ctx, key)
+	if err != nil {
+		return Lease{}, false, err
+	}
+
+	now := l.now()
+	if found && !current.Expired(now) {
+		return current, false, nil
+	}
+
+	lease := Lease{
+		Key:        key,
+		Holder:     holder,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+
+	var parents []github.Commit
+	if found {
+		parents = []github.Commit{{SHA: github.String(current.ID)}}
+	}
+
+	lease.ID, err = l.createLeaseCommit(ctx, lease, parents)
+	if err != nil {
+		return Lease{}, false, err
+	}
+
+	ref := &github.Reference{
+		Ref:    github.String(lockRef(key)),
+		Object: &github.GitObject{SHA: github.String(lease.ID)},
+	}
+	if found {
+		_, _, err = l.client.Git.UpdateRef(ctx, key.Owner, key.Repo, ref, false)
+	} else {
+		_, _, err = l.client.Git.CreateRef(ctx, key.Owner, key.Repo, ref)
+	}
+
+	if err != nil {
+		if !isUnprocessable(err) {
+			return Lease{}, false, errors.Wrapf(err, "failed to write lock ref for %s", key)
+		}
+
+		// another instance acquired the lock first
+		current, _, err := l.current(ctx, key)
+		if err != nil {
+			return Lease{}, false, err
+		}
+		return current, false, nil
+	}
+
+	return lease, true, nil
+}
+
+// Release replaces the lease with an expired copy, so that the next Acquire
+// takes the lock over without waiting. It returns ErrLeaseLost if the lock ref
+// no longer points to the lease.
+func (l *GitHubLocker) Release(ctx context.Context, lease Lease) error {
+	released := lease
+	released.ExpiresAt = l.now()
+
+	sha, err := l.createLeaseCommit(ctx, released, []github.Commit{{SHA: github.String(lease.ID)}})
+	if err != nil {
+		return err
+	}
+
+	ref := &github.Reference{
+		Ref:    github.String(lockRef(lease.Key)),
+		Object: &github.GitObject{SHA: github.String(sha)},
+	}
+	if _, _, err := l.client.Git.UpdateRef(ctx, lease.Key.Owner, lease.Key.Repo, ref, false); err != nil {
+		if isNotFound(err) || isUnprocessable(err) {
+			return errors.Wrapf(ErrLeaseLost, "failed to release lock %s held by %s", lease.Key, lease.Holder)
+		}
+		return errors.Wrapf(err, "failed to release lock %s", lease.Key)
+	}
+	return nil
+}
+
+// current returns the lease recorded by the lock ref and true, or false if
+// the lock ref does not exist.
+func (l *GitHubLocker) current(ctx context.Context, key Key) (Lease, bool, error) {
+	name := lockRef(key)
+
+	// GitHub lists refs sharing the prefix if there is no exact match, for
+	// example a lock for "release/1.0" when looking up "release/1"
+	refs, _, err := l.client.Git.GetRefs(ctx, key.Owner, key.Repo, name)
+	if err != nil {
+		if isNotFound(err) {
+			return Lease{}, false, nil
+		}
+		return Lease{}, false, errors.Wrapf(err, "failed to get lock ref for %s", key)
+	}
+
+	var sha string
+	for _, ref := range refs {
+		if ref.GetRef() == name {
+			sha = ref.GetObject().GetSHA()
+		}
+	}
+	if sha == "" {
+		return Lease{}, false, nil
+	}
+
+	commit, _, err := l.client.Git.GetCommit(ctx, key.Owner, key.Repo, sha)
+	if err != nil {
+		return Lease{}, false, errors.Wrapf(err, "failed to get lock commit %s for %s", sha, key)
+	}
+
+	var lease Lease
+	if err := json.Unmarshal([]byte(commit.GetMessage()), &lease); err != nil {
+		// treat unreadable leases as expired so they can be taken over
+		return Lease{ID: sha, Key: key}, true, nil
+	}
+	lease.ID = sha
+	lease.Key = key
+	return lease, true, nil
+}
+
+func (l *GitHubLocker) createLeaseCommit(ctx context.Context, lease Lease, parents []github.Commit) (string, error) {
+	message, err := json.Marshal(lease)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal lease")
+	}
+
+	tree, _, err := l.client.Git.CreateTree(ctx, lease.Key.Owner, lease.Key.Repo, "", []github.TreeEntry{
+		{
+			Path:    github.String("lease.json"),
+			Mode:    github.String("100644"),
+			Type:    github.String("blob"),
+			Content: github.String(string(message)),
+		},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create lock tree for %s", lease.Key)
+	}
+
+	commit, _, err := l.client.Git.CreateCommit(ctx, lease.Key.Owner, lease.Key.Repo, &github.Commit{
+		Message: github.String(string(message)),
+		Tree:    tree,
+		Parents: parents,
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create lock commit for %s", lease.Key)
+	}
+	return commit.GetSHA(), nil
+}
+
+func isNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+func isUnprocessable(err error) bool {
+	return hasStatus(err, http.StatusUnprocessableEntity)
+}
+
+func hasStatus(err error, status int) bool {
+	rerr, ok := err.(*github.ErrorResponse)
+	return ok && rerr.Response != nil && rerr.Response.StatusCode == status
+}
+
+// type assertion
+var _ Locker = &GitHubLocker{}
@@ -0,0 +1,185 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRefs implements the parts of the Git data API used by GitHubLocker,
+// including the failure of creating a ref that already exists.
+type fakeRefs struct {
+	mu      sync.Mutex
+	refs    map[string]string
+	commits map[string]github.Commit
+}
+
+func (f *fakeRefs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	const prefix = "/repos/owner/repo/git/"
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+
+	switch {
+	case r.Method == http.MethodPost && path == "trees":
+		writeJSON(w, http.StatusCreated, github.Tree{SHA: github.String("tree")})
+
+	case r.Method == http.MethodPost && path == "commits":
+		var body struct {
+			Message string   `json:"message"`
+			Parents []string `json:"parents"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		sha := fmt.Sprintf("commit%d", len(f.commits)+1)
+		commit := github.Commit{SHA: github.String(sha), Message: github.String(body.Message)}
+		for _, p := range body.Parents {
+			commit.Parents = append(commit.Parents, github.Commit{SHA: github.String(p)})
+		}
+		f.commits[sha] = commit
+		writeJSON(w, http.StatusCreated, commit)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "commits/"):
+		writeJSON(w, http.StatusOK, f.commits[strings.TrimPrefix(path, "commits/")])
+
+	case r.Method == http.MethodPost && path == "refs":
+		var ref struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&ref)
+
+		if _, ok := f.refs[ref.Ref]; ok {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Reference already exists"})
+			return
+		}
+		f.refs[ref.Ref] = ref.SHA
+		writeJSON(w, http.StatusCreated, reference(ref.Ref, ref.SHA))
+
+	case strings.HasPrefix(path, "refs/"):
+		name := "refs/" + strings.TrimPrefix(path, "refs/")
+		sha, ok := f.refs[name]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, reference(name, sha))
+		case http.MethodDelete:
+			delete(f.refs, name)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodPatch:
+			var update struct {
+				SHA string `json:"sha"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&update)
+
+			parents := f.commits[update.SHA].Parents
+			if len(parents) == 0 || parents[0].GetSHA() != sha {
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Update is not a fast forward"})
+				return
+			}
+			f.refs[name] = update.SHA
+			writeJSON(w, http.StatusOK, reference(name, update.SHA))
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func reference(name, sha string) github.Reference {
+	return github.Reference{
+		Ref:    github.String(name),
+		Object: &github.GitObject{SHA: github.String(sha)},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestGitHubLocker(t *testing.T) {
+	ctx := context.Background()
+	key := Key{Owner: "owner", Repo: "repo", Branch: "develop"}
+
+	fake := &fakeRefs{refs: make(map[string]string), commits: make(map[string]github.Commit)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	newLocker := func() *GitHubLocker {
+		l := NewGitHubLocker(client)
+		l.now = func() time.Time { return now }
+		return l
+	}
+	a, b := newLocker(), newLocker()
+
+	lease, ok, err := a.Acquire(ctx, key, "owner/repo#1", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, lease.ID, fake.refs["refs/bulldozer/lock/develop"])
+
+	held, ok, err := b.Acquire(ctx, key, "owner/repo#2", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "lock held by another instance must not be acquired")
+	assert.Equal(t, "owner/repo#1", held.Holder)
+	assert.Equal(t, lease.ID, held.ID)
+
+	require.NoError(t, a.Release(ctx, lease))
+	assert.NotEqual(t, lease.ID, fake.refs["refs/bulldozer/lock/develop"], "a released lease must be replaced")
+
+	lease, ok, err = b.Acquire(ctx, key, "owner/repo#2", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok, "released lock must be acquirable")
+
+	now = now.Add(2 * time.Minute)
+	stolen, ok, err := a.Acquire(ctx, key, "owner/repo#3", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok, "expired lock must be acquirable")
+
+	err = b.Release(ctx, lease)
+	assert.Equal(t, ErrLeaseLost, errors.Cause(err))
+	assert.Equal(t, stolen.ID, fake.refs["refs/bulldozer/lock/develop"], "releasing a stale lease must not release the current one")
+
+	queueKey := key
+	queueKey.Scope = ScopeQueue
+	queued, ok, err := b.Acquire(ctx, queueKey, "queue", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok, "locks with different scopes must be independent")
+	assert.Equal(t, queued.ID, fake.refs["refs/bulldozer/queue/develop"])
+}
@@ -0,0 +1,150 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ScopeQueue is the scope of the locks that serialize advancing the merge
+// queue of a branch. Rebases use the default, empty scope.
+const ScopeQueue = "queue"
+
+// ErrLeaseLost is returned when releasing a lease that expired and was taken
+// over by another holder, or whose lock no longer exists.
+var ErrLeaseLost = errors.New("lease is no longer held")
+
+// Key identifies a lock. Locks are scoped to the base branch of a
+// repository, so operations on different branches or repositories never
+// block each other. Scope separates locks for different purposes on the same
+// branch.
+type Key struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+	Scope  string `json:"scope,omitempty"`
+}
+
+func (k Key) String() string {
+	if k.Scope != "" {
+		return fmt.Sprintf("%s/%s:%s (%s)", k.Owner, k.Repo, k.Branch, k.Scope)
+	}
+	return fmt.Sprintf("%s/%s:%s", k.Owner, k.Repo, k.Branch)
+}
+
+// Lease is a held lock. A lease is only valid until it expires, after which
+// the lock can be acquired by another holder.
+type Lease struct {
+	// ID identifies the lease within the backend that granted it
+	ID string `json:"id,omitempty"`
+
+	Key        Key       `json:"key"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (l Lease) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// Locker grants leases on keyed locks.
+type Locker interface {
+	// Acquire tries to take the lock for key on behalf of holder for at most
+	// ttl. If the lock is taken, it returns the lease of the current holder
+	// and false.
+	Acquire(ctx context.Context, key Key, holder string, ttl time.Duration) (Lease, bool, error)
+
+	// Release gives up a lease. Releasing a lease that expired and was
+	// acquired by another holder does not affect the new holder and returns
+	// ErrLeaseLost.
+	Release(ctx context.Context, lease Lease) error
+}
+
+// Manager is a Locker that holds locks in process memory. It only prevents
+// conflicts within a single bulldozer instance.
+type Manager struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	leases map[Key]Lease
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		now:    func() time.Time { return time.Now().UTC() },
+		leases: make(map[Key]Lease),
+	}
+}
+
+func (m *Manager) Acquire(ctx context.Context, key Key, holder string, ttl time.Duration) (Lease, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if current, ok := m.leases[key]; ok && !current.Expired(now) {
+		return current, false, nil
+	}
+
+	lease := Lease{
+		Key:        key,
+		Holder:     holder,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	m.leases[key] = lease
+	return lease, true, nil
+}
+
+func (m *Manager) Release(ctx context.Context, lease Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current, ok := m.leases[lease.Key]; !ok || current != lease {
+		return ErrLeaseLost
+	}
+	delete(m.leases, lease.Key)
+	return nil
+}
+
+// Leases returns all unexpired leases, ordered by key.
+func (m *Manager) Leases(ctx context.Context) ([]Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var leases []Lease
+	for key, lease := range m.leases {
+		if lease.Expired(now) {
+			delete(m.leases, key)
+			continue
+		}
+		leases = append(leases, lease)
+	}
+
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].Key.String() < leases[j].Key.String()
+	})
+	return leases, nil
+}
+
+// type assertion
+var _ Locker = &Manager{}
@@ -0,0 +1,73 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	keyA := Key{Owner: "owner", Repo: "a", Branch: "develop"}
+	keyB := Key{Owner: "owner", Repo: "b", Branch: "develop"}
+
+	now := time.Now()
+	m := NewManager()
+	m.now = func() time.Time { return now }
+
+	lease, ok, err := m.Acquire(ctx, keyA, "owner/a#1", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	held, ok, err := m.Acquire(ctx, keyA, "owner/a#2", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "lock for the same key must not be acquired twice")
+	assert.Equal(t, "owner/a#1", held.Holder)
+
+	_, ok, err = m.Acquire(ctx, keyB, "owner/b#1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "locks for different keys must be independent")
+
+	leases, err := m.Leases(ctx)
+	require.NoError(t, err)
+	assert.Len(t, leases, 2)
+
+	require.NoError(t, m.Release(ctx, lease))
+	_, ok, err = m.Acquire(ctx, keyA, "owner/a#2", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	stolen, ok, err := m.Acquire(ctx, keyA, "owner/a#3", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "expired lock must be acquirable")
+
+	assert.Equal(t, ErrLeaseLost, m.Release(ctx, lease))
+	held, ok, err = m.Acquire(ctx, keyA, "owner/a#4", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "releasing a stale lease must not release the current one")
+	assert.Equal(t, stolen, held)
+
+	queueKey := keyA
+	queueKey.Scope = ScopeQueue
+	_, ok, err = m.Acquire(ctx, queueKey, "queue", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "locks with different scopes must be independent")
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	BackendMemory = "memory"
+	BackendGitHub = "github"
+)
+
+// Provider returns the Locker to use for operations performed with an
+// installation client.
+type Provider func(client *github.Client) Locker
+
+// MemoryProvider returns a Provider that uses the same in-memory Manager for
+// all installations.
+func MemoryProvider(m *Manager) Provider {
+	return func(client *github.Client) Locker {
+		return m
+	}
+}
+
+// GitHubProvider returns a Provider that coordinates through lock refs in
+// each repository.
+func GitHubProvider() Provider {
+	return func(client *github.Client) Locker {
+		return NewGitHubLocker(client)
+	}
+}
+
+// Recorder keeps track of the leases acquired through the Lockers it wraps so
+// that an instance can list the locks it holds, regardless of the backend.
+type Recorder struct {
+	mu     sync.Mutex
+	leases map[Key]Lease
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		leases: make(map[Key]Lease),
+	}
+}
+
+// Wrap returns a Provider that records the leases of the Lockers returned by
+// provider.
+func (r *Recorder) Wrap(provider Provider) Provider {
+	return func(client *github.Client) Locker {
+		return &recordingLocker{Locker: provider(client), recorder: r}
+	}
+}
+
+// Leases returns all unexpired leases held by this instance, ordered by key.
+func (r *Recorder) Leases(ctx context.Context) ([]Lease, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var leases []Lease
+	for key, lease := range r.leases {
+		if lease.Expired(now) {
+			delete(r.leases, key)
+			continue
+		}
+		leases = append(leases, lease)
+	}
+
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].Key.String() < leases[j].Key.String()
+	})
+	return leases, nil
+}
+
+type recordingLocker struct {
+	Locker
+	recorder *Recorder
+}
+
+func (l *recordingLocker) Acquire(ctx context.Context, key Key, holder string, ttl time.Duration) (Lease, bool, error) {
+	lease, acquired, err := l.Locker.Acquire(ctx, key, holder, ttl)
+	if err == nil && acquired {
+		l.recorder.mu.Lock()
+		l.recorder.leases[key] = lease
+		l.recorder.mu.Unlock()
+	}
+	return lease, acquired, err
+}
+
+func (l *recordingLocker) Release(ctx context.Context, lease Lease) error {
+	l.recorder.mu.Lock()
+	if current, ok := l.recorder.leases[lease.Key]; ok && current == lease {
+		delete(l.recorder.leases, lease.Key)
+	}
+	l.recorder.mu.Unlock()
+
+	return l.Locker.Release(ctx, lease)
+}
@@ -16,6 +16,9 @@ package pull
 
 import (
 	"context"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 // Context is the context for a pull request. It defines methods to get
@@ -38,22 +41,64 @@ type Context interface {
 	// string is formatted as "<owner>/<repository>#<number>"
 	Locator() string
 
+	// HeadSHA returns the SHA of the head commit of the pull request
+	HeadSHA() string
+
 	// Title returns the pull request title
 	Title(ctx context.Context) (string, error)
 
+	// Author returns the login of the user who opened the pull request
+	Author(ctx context.Context) (string, error)
+
+	// AuthorAssociation returns the relationship of the author to the
+	// repository, one of the AuthorAssociation constants
+	AuthorAssociation(ctx context.Context) (string, error)
+
 	// Body returns the pull request body
 	Body(ctx context.Context) (string, error)
 
+	// IsDraft returns true if the pull request is a draft
+	IsDraft(ctx context.Context) (bool, error)
+
 	// RequiredStatuses returns the names of the required status
 	// checks for the pull request.
 	RequiredStatuses(ctx context.Context) ([]string, error)
 
-	// CurrentSuccessStatuses returns the names of all currently
-	// successful status checks for the pull request.
-	CurrentSuccessStatuses(ctx context.Context) ([]string, error)
+	// Statuses returns the latest commit status of each context for the
+	// head commit of the pull request.
+	Statuses(ctx context.Context) ([]Status, error)
+
+	// CheckRuns returns the latest check run of each name for the head
+	// commit of the pull request.
+	CheckRuns(ctx context.Context) ([]CheckRun, error)
+
+	// Reviews lists all submitted reviews of the pull request, oldest first.
+	Reviews(ctx context.Context) ([]Review, error)
+
+	// ReviewRequirements returns the review requirements of the branch
+	// protection of the base branch.
+	ReviewRequirements(ctx context.Context) (ReviewRequirements, error)
 
-	// Comments lists all comments on a Pull Request
-	Comments(ctx context.Context) ([]string, error)
+	// ChangedFiles lists the paths of all files changed by the pull request.
+	// It returns ErrTooManyChangedFiles if the pull request changes more
+	// files than GitHub lists.
+	ChangedFiles(ctx context.Context) ([]string, error)
+
+	// CodeOwners returns the content of the CODEOWNERS file of the base
+	// branch, or nil if there is none.
+	CodeOwners(ctx context.Context) ([]byte, error)
+
+	// TeamMembers returns the logins of the members of a team, identified
+	// as "<org>/<team-slug>".
+	TeamMembers(ctx context.Context, team string) ([]string, error)
+
+	// Comments lists all comments on a Pull Request in the order they were
+	// created
+	Comments(ctx context.Context) ([]Comment, error)
+
+	// Permission returns the permission of a user on the repository, one of
+	// "admin", "write", "read", or "none".
+	Permission(ctx context.Context, user string) (string, error)
 
 	// Labels lists all labels on a Pull Request
 	Labels(ctx context.Context) ([]string, error)
@@ -64,3 +109,93 @@ type Context interface {
 	// The base branch will always be unprefixed.
 	Branches(ctx context.Context) (base string, head string, err error)
 }
+
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	StatusError   = "error"
+)
+
+// Status is a commit status reported through the GitHub Statuses API.
+type Status struct {
+	Context string
+	// State is one of StatusPending, StatusSuccess, StatusFailure, or
+	// StatusError
+	State       string
+	Description string
+	TargetURL   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// CheckRun is a check reported through the GitHub Checks API.
+type CheckRun struct {
+	Name string
+	// Status is one of "queued", "in_progress", or "completed"
+	Status string
+	// Conclusion is set once the check run is completed, e.g. "success",
+	// "neutral", "skipped", or "failure"
+	Conclusion string
+}
+
+const (
+	ReviewApproved         = "APPROVED"
+	ReviewChangesRequested = "CHANGES_REQUESTED"
+	ReviewCommented        = "COMMENTED"
+	ReviewDismissed        = "DISMISSED"
+)
+
+// Review is a submitted pull request review.
+type Review struct {
+	Author string
+	// AuthorID is the GitHub user ID of Author
+	AuthorID int64
+	// State is one of ReviewApproved, ReviewChangesRequested,
+	// ReviewCommented, or ReviewDismissed
+	State string
+	// CommitID is the SHA of the head commit when the review was submitted
+	CommitID    string
+	SubmittedAt time.Time
+}
+
+// ReviewRequirements are the review settings of a protected branch.
+type ReviewRequirements struct {
+	// RequiredApprovals is the number of approving reviews required to
+	// merge, or 0 if reviews are not required
+	RequiredApprovals int
+	// DismissStaleReviews is true if approvals are dismissed when new
+	// commits are pushed
+	DismissStaleReviews bool
+	// RequireCodeOwnerReviews is true if a code owner must approve changes
+	// to the files they own
+	RequireCodeOwnerReviews bool
+}
+
+// MaxChangedFiles is the maximum number of files GitHub lists for a pull
+// request.
+const MaxChangedFiles = 3000
+
+// ErrTooManyChangedFiles is returned by ChangedFiles if the pull request
+// changes more than MaxChangedFiles files, so that the list is incomplete.
+var ErrTooManyChangedFiles = errors.New("pull request changes too many files to list")
+
+// Comment is a comment on a pull request or on one of its changes.
+type Comment struct {
+	Body   string
+	Author string
+	// AuthorAssociation is one of the AuthorAssociation constants
+	AuthorAssociation string
+	CreatedAt         time.Time
+}
+
+// Relationships of a pull request author to the repository.
+const (
+	AuthorAssociationOwner                = "OWNER"
+	AuthorAssociationMember               = "MEMBER"
+	AuthorAssociationCollaborator         = "COLLABORATOR"
+	AuthorAssociationContributor          = "CONTRIBUTOR"
+	AuthorAssociationFirstTimeContributor = "FIRST_TIME_CONTRIBUTOR"
+	AuthorAssociationFirstTimer           = "FIRST_TIMER"
+	AuthorAssociationNone                 = "NONE"
+)
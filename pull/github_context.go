@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/google/go-github/github"
 	"github.com/pkg/errors"
@@ -34,9 +36,17 @@ type GithubContext struct {
 	pr     *github.PullRequest
 
 	// cached fields
-	comments         []string
+	draft            *bool
+	comments         []Comment
+	permissions      map[string]string
 	requiredStatuses []string
-	successStatuses  []string
+	statuses         []Status
+	checkRuns        []CheckRun
+	reviews          []Review
+	reviewReqs       *ReviewRequirements
+	changedFiles     []string
+	codeOwners       *[]byte
+	teamMembers      map[string][]string
 }
 
 func NewGithubContext(client *github.Client, pr *github.PullRequest, owner, repo string, number int) Context {
@@ -66,16 +76,54 @@ func (ghc *GithubContext) Locator() string {
 	return fmt.Sprintf("%s/%s#%d", ghc.owner, ghc.repo, ghc.number)
 }
 
+func (ghc *GithubContext) HeadSHA() string {
+	return ghc.pr.GetHead().GetSHA()
+}
+
 func (ghc *GithubContext) Title(ctx context.Context) (string, error) {
 	return ghc.pr.GetTitle(), nil
 }
 
+func (ghc *GithubContext) Author(ctx context.Context) (string, error) {
+	return ghc.pr.GetUser().GetLogin(), nil
+}
+
+func (ghc *GithubContext) AuthorAssociation(ctx context.Context) (string, error) {
+	return ghc.pr.GetAuthorAssociation(), nil
+}
+
 func (ghc *GithubContext) Body(ctx context.Context) (string, error) {
 	return ghc.pr.GetBody(), nil
 }
 
-func (ghc *GithubContext) Comments(ctx context.Context) ([]string, error) {
+// draftPreviewHeader enables the draft field of pull requests, which is
+// still a preview of the GitHub API
+const draftPreviewHeader = "application/vnd.github.shadow-cat-preview+json"
+
+func (ghc *GithubContext) IsDraft(ctx context.Context) (bool, error) {
+	if ghc.draft == nil {
+		u := fmt.Sprintf("repos/%s/%s/pulls/%d", ghc.owner, ghc.repo, ghc.number)
+		req, err := ghc.client.NewRequest("GET", u, nil)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Accept", draftPreviewHeader)
+
+		var pr struct {
+			Draft bool `json:"draft"`
+		}
+		if _, err := ghc.client.Do(ctx, req, &pr); err != nil {
+			return false, errors.Wrapf(err, "failed to get draft state of %s", ghc.Locator())
+		}
+		ghc.draft = &pr.Draft
+	}
+
+	return *ghc.draft, nil
+}
+
+func (ghc *GithubContext) Comments(ctx context.Context) ([]Comment, error) {
 	if ghc.comments == nil {
+		ghc.comments = []Comment{}
 
 		prCommentOpts := &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
 		for {
@@ -85,7 +133,12 @@ func (ghc *GithubContext) Comments(ctx context.Context) ([]string, error) {
 			}
 
 			for _, c := range comments {
-				ghc.comments = append(ghc.comments, c.GetBody())
+				ghc.comments = append(ghc.comments, Comment{
+					Body:              c.GetBody(),
+					Author:            c.GetUser().GetLogin(),
+					AuthorAssociation: c.GetAuthorAssociation(),
+					CreatedAt:         c.GetCreatedAt(),
+				})
 			}
 
 			if res.NextPage == 0 {
@@ -102,7 +155,12 @@ func (ghc *GithubContext) Comments(ctx context.Context) ([]string, error) {
 			}
 
 			for _, c := range comments {
-				ghc.comments = append(ghc.comments, c.GetBody())
+				ghc.comments = append(ghc.comments, Comment{
+					Body:              c.GetBody(),
+					Author:            c.GetUser().GetLogin(),
+					AuthorAssociation: c.GetAuthorAssociation(),
+					CreatedAt:         c.GetCreatedAt(),
+				})
 			}
 
 			if res.NextPage == 0 {
@@ -110,11 +168,197 @@ func (ghc *GithubContext) Comments(ctx context.Context) ([]string, error) {
 			}
 			issueCommentOpts.Page = res.NextPage
 		}
+
+		sort.SliceStable(ghc.comments, func(i, j int) bool {
+			return ghc.comments[i].CreatedAt.Before(ghc.comments[j].CreatedAt)
+		})
 	}
 
 	return ghc.comments, nil
 }
 
+func (ghc *GithubContext) Permission(ctx context.Context, user string) (string, error) {
+	if permission, ok := ghc.permissions[user]; ok {
+		return permission, nil
+	}
+
+	level, _, err := ghc.client.Repositories.GetPermissionLevel(ctx, ghc.owner, ghc.repo, user)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get permission of %s on %s/%s", user, ghc.owner, ghc.repo)
+	}
+
+	if ghc.permissions == nil {
+		ghc.permissions = make(map[string]string)
+	}
+	ghc.permissions[user] = level.GetPermission()
+	return level.GetPermission(), nil
+}
+
+func (ghc *GithubContext) Reviews(ctx context.Context) ([]Review, error) {
+	if ghc.reviews == nil {
+		opts := &github.ListOptions{PerPage: 100}
+		reviews := []Review{}
+
+		for {
+			page, res, err := ghc.client.PullRequests.ListReviews(ctx, ghc.owner, ghc.repo, ghc.number, opts)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list reviews for %s", ghc.Locator())
+			}
+
+			for _, r := range page {
+				reviews = append(reviews, Review{
+					Author:      r.GetUser().GetLogin(),
+					AuthorID:    r.GetUser().GetID(),
+					State:       r.GetState(),
+					CommitID:    r.GetCommitID(),
+					SubmittedAt: r.GetSubmittedAt(),
+				})
+			}
+
+			if res.NextPage == 0 {
+				break
+			}
+			opts.Page = res.NextPage
+		}
+
+		ghc.reviews = reviews
+	}
+
+	return ghc.reviews, nil
+}
+
+func (ghc *GithubContext) ReviewRequirements(ctx context.Context) (ReviewRequirements, error) {
+	if ghc.reviewReqs == nil {
+		enforcement, _, err := ghc.client.Repositories.GetPullRequestReviewEnforcement(ctx, ghc.owner, ghc.repo, ghc.pr.GetBase().GetRef())
+		if err != nil {
+			if isNotFound(err) {
+				// Github returns 404 when reviews are not required
+				ghc.reviewReqs = &ReviewRequirements{}
+				return *ghc.reviewReqs, nil
+			}
+			return ReviewRequirements{}, errors.Wrapf(err, "cannot get review requirements for %s", ghc.Locator())
+		}
+
+		ghc.reviewReqs = &ReviewRequirements{
+			RequiredApprovals:       enforcement.RequiredApprovingReviewCount,
+			DismissStaleReviews:     enforcement.DismissStaleReviews,
+			RequireCodeOwnerReviews: enforcement.RequireCodeOwnerReviews,
+		}
+		// required reviews always need at least one approval
+		if ghc.reviewReqs.RequiredApprovals == 0 {
+			ghc.reviewReqs.RequiredApprovals = 1
+		}
+	}
+
+	return *ghc.reviewReqs, nil
+}
+
+func (ghc *GithubContext) ChangedFiles(ctx context.Context) ([]string, error) {
+	if ghc.changedFiles == nil {
+		if ghc.pr.GetChangedFiles() > MaxChangedFiles {
+			return nil, errors.Wrapf(ErrTooManyChangedFiles, "%s changes %d files", ghc.Locator(), ghc.pr.GetChangedFiles())
+		}
+
+		opts := &github.ListOptions{PerPage: 100}
+		changedFiles := []string{}
+
+		for {
+			files, res, err := ghc.client.PullRequests.ListFiles(ctx, ghc.owner, ghc.repo, ghc.number, opts)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list changed files for %s", ghc.Locator())
+			}
+
+			for _, f := range files {
+				changedFiles = append(changedFiles, f.GetFilename())
+			}
+
+			if res.NextPage == 0 {
+				break
+			}
+			opts.Page = res.NextPage
+		}
+
+		// pull requests from list endpoints don't include the number of
+		// changed files, so a full list may have been truncated
+		if ghc.pr.GetChangedFiles() == 0 && len(changedFiles) >= MaxChangedFiles {
+			return nil, errors.Wrapf(ErrTooManyChangedFiles, "%s changes at least %d files", ghc.Locator(), len(changedFiles))
+		}
+
+		ghc.changedFiles = changedFiles
+	}
+
+	return ghc.changedFiles, nil
+}
+
+// CodeOwnersPaths are the locations GitHub reads a CODEOWNERS file from, in
+// order of precedence.
+var CodeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+func (ghc *GithubContext) CodeOwners(ctx context.Context) ([]byte, error) {
+	if ghc.codeOwners == nil {
+		var content []byte
+		for _, path := range CodeOwnersPaths {
+			c, err := FetchFileContents(ctx, ghc.client, ghc.owner, ghc.repo, ghc.pr.GetBase().GetRef(), path)
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				content = c
+				break
+			}
+		}
+		ghc.codeOwners = &content
+	}
+
+	return *ghc.codeOwners, nil
+}
+
+func (ghc *GithubContext) TeamMembers(ctx context.Context, team string) ([]string, error) {
+	if members, ok := ghc.teamMembers[team]; ok {
+		return members, nil
+	}
+	if members, ok := teamMembers.get(ghc.owner, team); ok {
+		return members, nil
+	}
+
+	parts := strings.SplitN(team, "/", 2)
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid team %q, expected <org>/<team-slug>", team)
+	}
+
+	members := []string{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		u := fmt.Sprintf("orgs/%s/teams/%s/members?per_page=%d&page=%d", parts[0], parts[1], opts.PerPage, opts.Page)
+		req, err := ghc.client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var users []*github.User
+		res, err := ghc.client.Do(ctx, req, &users)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list members of team %s", team)
+		}
+
+		for _, user := range users {
+			members = append(members, user.GetLogin())
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	if ghc.teamMembers == nil {
+		ghc.teamMembers = make(map[string][]string)
+	}
+	ghc.teamMembers[team] = members
+	teamMembers.set(ghc.owner, team, members)
+	return members, nil
+}
+
 func (ghc *GithubContext) RequiredStatuses(ctx context.Context) ([]string, error) {
 	if ghc.requiredStatuses == nil {
 		requiredStatuses, _, err := ghc.client.Repositories.GetRequiredStatusChecks(ctx, ghc.owner, ghc.repo, ghc.pr.GetBase().GetRef())
@@ -136,21 +380,60 @@ func isNotFound(err error) bool {
 	return ok && rerr.Response.StatusCode == http.StatusNotFound
 }
 
-func (ghc *GithubContext) CurrentSuccessStatuses(ctx context.Context) ([]string, error) {
-	if ghc.successStatuses == nil {
+func (ghc *GithubContext) Statuses(ctx context.Context) ([]Status, error) {
+	if ghc.statuses == nil {
 		opts := &github.ListOptions{PerPage: 100}
-		var successStatuses []string
+		statuses := []Status{}
 
 		for {
 			combinedStatus, res, err := ghc.client.Repositories.GetCombinedStatus(ctx, ghc.owner, ghc.repo, ghc.pr.GetHead().GetSHA(), opts)
 			if err != nil {
-				return ghc.successStatuses, errors.Wrapf(err, "cannot get combined status for SHA %s on %s", ghc.pr.GetHead().GetSHA(), ghc.Locator())
+				return nil, errors.Wrapf(err, "cannot get combined status for SHA %s on %s", ghc.pr.GetHead().GetSHA(), ghc.Locator())
 			}
 
 			for _, s := range combinedStatus.Statuses {
-				if s.GetState() == "success" {
-					successStatuses = append(successStatuses, s.GetContext())
-				}
+				statuses = append(statuses, Status{
+					Context:     s.GetContext(),
+					State:       s.GetState(),
+					Description: s.GetDescription(),
+					TargetURL:   s.GetTargetURL(),
+					CreatedAt:   s.GetCreatedAt(),
+					UpdatedAt:   s.GetUpdatedAt(),
+				})
+			}
+
+			if res.NextPage == 0 {
+				break
+			}
+			opts.Page = res.NextPage
+		}
+
+		ghc.statuses = statuses
+	}
+
+	return ghc.statuses, nil
+}
+
+func (ghc *GithubContext) CheckRuns(ctx context.Context) ([]CheckRun, error) {
+	if ghc.checkRuns == nil {
+		opts := &github.ListCheckRunsOptions{
+			Filter:      github.String("latest"),
+			ListOptions: github.ListOptions{PerPage: 100},
+		}
+		checkRuns := []CheckRun{}
+
+		for {
+			result, res, err := ghc.client.Checks.ListCheckRunsForRef(ctx, ghc.owner, ghc.repo, ghc.pr.GetHead().GetSHA(), opts)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot list check runs for SHA %s on %s", ghc.pr.GetHead().GetSHA(), ghc.Locator())
+			}
+
+			for _, run := range result.CheckRuns {
+				checkRuns = append(checkRuns, CheckRun{
+					Name:       run.GetName(),
+					Status:     run.GetStatus(),
+					Conclusion: run.GetConclusion(),
+				})
 			}
 
 			if res.NextPage == 0 {
@@ -159,10 +442,10 @@ func (ghc *GithubContext) CurrentSuccessStatuses(ctx context.Context) ([]string,
 			opts.Page = res.NextPage
 		}
 
-		ghc.successStatuses = successStatuses
+		ghc.checkRuns = checkRuns
 	}
 
-	return ghc.successStatuses, nil
+	return ghc.checkRuns, nil
 }
 
 func (ghc *GithubContext) Branches(ctx context.Context) (base string, head string, err error) {
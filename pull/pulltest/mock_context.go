@@ -29,23 +29,61 @@ type MockPullContext struct {
 	TitleValue    string
 	TitleErrValue error
 
+	AuthorValue            string
+	AuthorAssociationValue string
+	AuthorErrValue         error
+
 	BodyValue    string
 	BodyErrValue error
 
+	DraftValue    bool
+	DraftErrValue error
+
 	LocatorValue string
 
+	HeadSHAValue string
+
 	LabelValue    []string
 	LabelErrValue error
 
+	// CommentValue are bodies of comments added to CommentsValue without
+	// an author
 	CommentValue    []string
+	CommentsValue   []pull.Comment
 	CommentErrValue error
 
+	// PermissionValue maps logins to their permission on the repository
+	PermissionValue    map[string]string
+	PermissionErrValue error
+
 	RequiredStatusesValue    []string
 	RequiredStatusesErrValue error
 
+	// SuccessStatusesValue are contexts added to StatusesValue with the
+	// success state
 	SuccessStatusesValue    []string
+	StatusesValue           []pull.Status
 	SuccessStatusesErrValue error
 
+	CheckRunsValue    []pull.CheckRun
+	CheckRunsErrValue error
+
+	ReviewsValue    []pull.Review
+	ReviewsErrValue error
+
+	ReviewRequirementsValue    pull.ReviewRequirements
+	ReviewRequirementsErrValue error
+
+	ChangedFilesValue    []string
+	ChangedFilesErrValue error
+
+	CodeOwnersValue    []byte
+	CodeOwnersErrValue error
+
+	// TeamMembersValue maps "<org>/<team-slug>" to the logins of its members
+	TeamMembersValue    map[string][]string
+	TeamMembersErrValue error
+
 	BranchBase     string
 	BranchName     string
 	BranchErrValue error
@@ -70,24 +108,79 @@ func (c *MockPullContext) Locator() string {
 	return "pulltest/context#1"
 }
 
+func (c *MockPullContext) HeadSHA() string {
+	return c.HeadSHAValue
+}
+
 func (c *MockPullContext) Title(ctx context.Context) (string, error) {
 	return c.TitleValue, c.TitleErrValue
 }
 
+func (c *MockPullContext) Author(ctx context.Context) (string, error) {
+	return c.AuthorValue, c.AuthorErrValue
+}
+
+func (c *MockPullContext) AuthorAssociation(ctx context.Context) (string, error) {
+	return c.AuthorAssociationValue, c.AuthorErrValue
+}
+
 func (c *MockPullContext) Body(ctx context.Context) (string, error) {
 	return c.BodyValue, c.BodyErrValue
 }
 
-func (c *MockPullContext) Comments(ctx context.Context) ([]string, error) {
-	return c.CommentValue, c.CommentErrValue
+func (c *MockPullContext) IsDraft(ctx context.Context) (bool, error) {
+	return c.DraftValue, c.DraftErrValue
+}
+
+func (c *MockPullContext) Comments(ctx context.Context) ([]pull.Comment, error) {
+	comments := append([]pull.Comment(nil), c.CommentsValue...)
+	for _, body := range c.CommentValue {
+		comments = append(comments, pull.Comment{Body: body})
+	}
+	return comments, c.CommentErrValue
+}
+
+func (c *MockPullContext) Permission(ctx context.Context, user string) (string, error) {
+	if permission, ok := c.PermissionValue[user]; ok {
+		return permission, c.PermissionErrValue
+	}
+	return "none", c.PermissionErrValue
 }
 
 func (c *MockPullContext) RequiredStatuses(ctx context.Context) ([]string, error) {
 	return c.RequiredStatusesValue, c.RequiredStatusesErrValue
 }
 
-func (c *MockPullContext) CurrentSuccessStatuses(ctx context.Context) ([]string, error) {
-	return c.SuccessStatusesValue, c.SuccessStatusesErrValue
+func (c *MockPullContext) Statuses(ctx context.Context) ([]pull.Status, error) {
+	statuses := append([]pull.Status(nil), c.StatusesValue...)
+	for _, name := range c.SuccessStatusesValue {
+		statuses = append(statuses, pull.Status{Context: name, State: pull.StatusSuccess})
+	}
+	return statuses, c.SuccessStatusesErrValue
+}
+
+func (c *MockPullContext) CheckRuns(ctx context.Context) ([]pull.CheckRun, error) {
+	return c.CheckRunsValue, c.CheckRunsErrValue
+}
+
+func (c *MockPullContext) Reviews(ctx context.Context) ([]pull.Review, error) {
+	return c.ReviewsValue, c.ReviewsErrValue
+}
+
+func (c *MockPullContext) ReviewRequirements(ctx context.Context) (pull.ReviewRequirements, error) {
+	return c.ReviewRequirementsValue, c.ReviewRequirementsErrValue
+}
+
+func (c *MockPullContext) ChangedFiles(ctx context.Context) ([]string, error) {
+	return c.ChangedFilesValue, c.ChangedFilesErrValue
+}
+
+func (c *MockPullContext) CodeOwners(ctx context.Context) ([]byte, error) {
+	return c.CodeOwnersValue, c.CodeOwnersErrValue
+}
+
+func (c *MockPullContext) TeamMembers(ctx context.Context, team string) ([]string, error) {
+	return c.TeamMembersValue[team], c.TeamMembersErrValue
 }
 
 func (c *MockPullContext) Branches(ctx context.Context) (base string, head string, err error) {
@@ -0,0 +1,84 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pull
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// TeamMembersTTL is how long the members of a team are cached across
+// GithubContext instances. Changes to a team, including removed members, take
+// up to this long to affect evaluation.
+const TeamMembersTTL = 5 * time.Minute
+
+type teamMembersEntry struct {
+	members   []string
+	expiresAt time.Time
+}
+
+// teamMembersKey identifies a team as seen by the installation for the owner
+// of a repository. Clients are created per webhook, but an owner has a single
+// installation, and installations may differ in which teams they can read, so
+// entries are never shared between owners.
+type teamMembersKey struct {
+	owner string
+	team  string
+}
+
+// teamMembersCache caches team members by repository owner and
+// "<org>/<team-slug>". Expired entries are evicted when an entry is added.
+type teamMembersCache struct {
+	mu      sync.Mutex
+	entries map[teamMembersKey]teamMembersEntry
+	now     func() time.Time
+}
+
+var teamMembers = &teamMembersCache{
+	entries: make(map[teamMembersKey]teamMembersEntry),
+	now:     time.Now,
+}
+
+func newTeamMembersKey(owner, team string) teamMembersKey {
+	return teamMembersKey{owner: strings.ToLower(owner), team: strings.ToLower(team)}
+}
+
+func (c *teamMembersCache) get(owner, team string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[newTeamMembersKey(owner, team)]
+	if !ok || c.now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.members, true
+}
+
+func (c *teamMembersCache) set(owner, team string, members []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[newTeamMembersKey(owner, team)] = teamMembersEntry{
+		members:   members,
+		expiresAt: now.Add(TeamMembersTTL),
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is a Store that keeps queues in process memory. Queues are lost
+// when the process exits.
+type MemoryStore struct {
+	mu     sync.Mutex
+	queues map[Key][]Entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		queues: make(map[Key][]Entry),
+	}
+}
+
+func (s *MemoryStore) LoadQueue(ctx context.Context, key Key) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return copyEntries(s.queues[key]), nil
+}
+
+func (s *MemoryStore) UpdateQueue(ctx context.Context, key Key, fn func([]Entry) ([]Entry, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := fn(copyEntries(s.queues[key]))
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		delete(s.queues, key)
+		return nil
+	}
+	s.queues[key] = copyEntries(entries)
+	return nil
+}
+
+func copyEntries(entries []Entry) []Entry {
+	if entries == nil {
+		return nil
+	}
+	return append([]Entry(nil), entries...)
+}
+
+// type assertion
+var _ Store = &MemoryStore{}
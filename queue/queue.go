@@ -0,0 +1,369 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type State string
+
+const (
+	StateQueued       State = "queued"
+	StateUpdating     State = "updating"
+	StateWaitingForCI State = "waiting_for_ci"
+	StateMerging      State = "merging"
+	StateDone         State = "done"
+	StateFailed       State = "failed"
+)
+
+// ActiveTimeout is the time after which an entry that has not changed state
+// is no longer considered active. This prevents a pull request whose CI never
+// reports back from blocking the queue forever.
+const ActiveTimeout = 2 * time.Hour
+
+// RetainTerminal is how long entries in a terminal state are kept for
+// inspection before they are pruned from the queue.
+const RetainTerminal = 24 * time.Hour
+
+var transitions = map[State][]State{
+	StateQueued:       {StateUpdating, StateWaitingForCI, StateMerging, StateDone, StateFailed},
+	StateUpdating:     {StateQueued, StateWaitingForCI, StateDone, StateFailed},
+	StateWaitingForCI: {StateQueued, StateUpdating, StateMerging, StateDone, StateFailed},
+	StateMerging:      {StateWaitingForCI, StateDone, StateFailed},
+	StateDone:         {},
+	StateFailed:       {StateQueued},
+}
+
+// Terminal returns true if no further work is expected for an entry in this
+// state.
+func (s State) Terminal() bool {
+	return s == StateDone || s == StateFailed
+}
+
+// Active returns true if an entry in this state holds the head of the queue.
+func (s State) Active() bool {
+	return s == StateUpdating || s == StateWaitingForCI || s == StateMerging
+}
+
+func (s State) canTransitionTo(to State) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Key identifies a queue. Each base branch of each repository has its own
+// independent queue.
+type Key struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s/%s:%s", k.Owner, k.Repo, k.Branch)
+}
+
+// Entry is a pull request in a queue.
+type Entry struct {
+	Number     int       `json:"number"`
+	State      State     `json:"state"`
+	Reason     string    `json:"reason,omitempty"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (e Entry) isActive(now time.Time) bool {
+	return e.State.Active() && now.Sub(e.UpdatedAt) < ActiveTimeout
+}
+
+// Store persists the content of queues. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// LoadQueue returns the entries of a queue in order. A queue that was
+	// never saved has no entries.
+	LoadQueue(ctx context.Context, key Key) ([]Entry, error)
+
+	// UpdateQueue replaces the entries of a queue with the entries returned
+	// by fn, which is called with the current entries. The load and the
+	// save must be atomic with respect to all other updates of the queue,
+	// including updates by other processes sharing the store. If fn returns
+	// an error, the queue is not changed and the error is returned.
+	UpdateQueue(ctx context.Context, key Key, fn func([]Entry) ([]Entry, error)) error
+}
+
+var (
+	ErrNotQueued         = errors.New("pull request is not queued")
+	ErrInvalidTransition = errors.New("invalid queue state transition")
+)
+
+// Queue orders the pull requests that bulldozer updates and merges for each
+// base branch. Only the first non-terminal entry of a queue is worked on at
+// any time. All changes are made with Store.UpdateQueue, so instances that
+// share a store do not overwrite each other's changes.
+type Queue struct {
+	store Store
+	now   func() time.Time
+}
+
+func New(store Store) *Queue {
+	return &Queue{
+		store: store,
+		now:   func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Enqueue adds a pull request to the end of a queue and returns its entry and
+// 1-based position. Pull requests that are already waiting keep their
+// position; pull requests in a terminal state are moved to the end.
+func (q *Queue) Enqueue(ctx context.Context, key Key, number int) (Entry, int, error) {
+	var entry Entry
+	var pos int
+
+	err := q.update(ctx, key, func(entries []Entry, now time.Time) ([]Entry, error) {
+		if idx := indexOf(entries, number); idx >= 0 {
+			if !entries[idx].State.Terminal() {
+				entry, pos = entries[idx], position(entries, number)
+				return entries, nil
+			}
+			entries = append(entries[:idx], entries[idx+1:]...)
+		}
+
+		entry = Entry{
+			Number:     number,
+			State:      StateQueued,
+			EnqueuedAt: now,
+			UpdatedAt:  now,
+		}
+		entries = append(entries, entry)
+		pos = position(entries, number)
+		return entries, nil
+	})
+	if err != nil {
+		return Entry{}, 0, err
+	}
+	return entry, pos, nil
+}
+
+// Remove deletes a pull request from a queue. It returns true if the pull
+// request was present.
+func (q *Queue) Remove(ctx context.Context, key Key, number int) (bool, error) {
+	var removed bool
+
+	err := q.update(ctx, key, func(entries []Entry, now time.Time) ([]Entry, error) {
+		idx := indexOf(entries, number)
+		if idx < 0 {
+			return entries, nil
+		}
+		removed = true
+		return append(entries[:idx], entries[idx+1:]...), nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return removed, nil
+}
+
+// Transition moves a queued pull request to a new state. It returns
+// ErrNotQueued if the pull request is not in the queue and
+// ErrInvalidTransition if the current state cannot move to the new state.
+// Transitioning to the current state only updates the reason.
+func (q *Queue) Transition(ctx context.Context, key Key, number int, to State, reason string) (Entry, error) {
+	return q.transition(ctx, key, number, "", to, reason)
+}
+
+// TransitionFrom is like Transition, but only moves the pull request if it is
+// in state from. Otherwise it returns ErrInvalidTransition.
+func (q *Queue) TransitionFrom(ctx context.Context, key Key, number int, from, to State, reason string) (Entry, error) {
+	return q.transition(ctx, key, number, from, to, reason)
+}
+
+func (q *Queue) transition(ctx context.Context, key Key, number int, from, to State, reason string) (Entry, error) {
+	var entry Entry
+
+	err := q.update(ctx, key, func(entries []Entry, now time.Time) ([]Entry, error) {
+		idx := indexOf(entries, number)
+		if idx < 0 {
+			return nil, ErrNotQueued
+		}
+
+		entry = entries[idx]
+		if from != "" && entry.State != from {
+			return nil, errors.Wrapf(ErrInvalidTransition, "%s#%d: %s is not %s", key, number, entry.State, from)
+		}
+		if entry.State != to && !entry.State.canTransitionTo(to) {
+			return nil, errors.Wrapf(ErrInvalidTransition, "%s#%d: %s -> %s", key, number, entry.State, to)
+		}
+
+		entry.State = to
+		entry.Reason = reason
+		entry.UpdatedAt = now
+		entries[idx] = entry
+		return entries, nil
+	})
+	if err != nil {
+		if errors.Cause(err) == ErrInvalidTransition {
+			return entry, err
+		}
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// Get returns the entry for a pull request and true, or false if the pull
+// request is not in the queue.
+func (q *Queue) Get(ctx context.Context, key Key, number int) (Entry, bool, error) {
+	entries, err := q.load(ctx, key)
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	if idx := indexOf(entries, number); idx >= 0 {
+		return entries[idx], true, nil
+	}
+	return Entry{}, false, nil
+}
+
+// Entries returns all entries of a queue in order, including entries in a
+// terminal state.
+func (q *Queue) Entries(ctx context.Context, key Key) ([]Entry, error) {
+	return q.load(ctx, key)
+}
+
+// Position returns the 1-based position of a pull request among the
+// non-terminal entries of a queue, or 0 if it is not waiting in the queue.
+func (q *Queue) Position(ctx context.Context, key Key, number int) (int, error) {
+	entries, err := q.load(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return position(entries, number), nil
+}
+
+// Active returns the entry currently being worked on, if any.
+func (q *Queue) Active(ctx context.Context, key Key) (Entry, bool, error) {
+	entries, err := q.load(ctx, key)
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	for _, e := range entries {
+		if e.State.Active() {
+			return e, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+// Next returns the first queued entry if no entry is active. Entries that
+// were active for longer than ActiveTimeout are failed so that they no
+// longer block the queue.
+func (q *Queue) Next(ctx context.Context, key Key) (Entry, bool, error) {
+	var next Entry
+	var ok bool
+
+	err := q.update(ctx, key, func(entries []Entry, now time.Time) ([]Entry, error) {
+		for _, e := range entries {
+			if e.State.Active() {
+				return entries, nil
+			}
+		}
+		for _, e := range entries {
+			if e.State == StateQueued {
+				next, ok = e, true
+				break
+			}
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return next, ok, nil
+}
+
+// load returns the entries of a queue as prepared by prepare. Read-only
+// operations see stale entries as expired, which is persisted by the next
+// change of the queue.
+func (q *Queue) load(ctx context.Context, key Key) ([]Entry, error) {
+	entries, err := q.store.LoadQueue(ctx, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load queue %s", key)
+	}
+	return prepare(entries, q.now()), nil
+}
+
+// update atomically applies fn to the prepared entries of a queue and saves
+// the result. Errors returned by fn are returned unchanged.
+func (q *Queue) update(ctx context.Context, key Key, fn func(entries []Entry, now time.Time) ([]Entry, error)) error {
+	var fnErr error
+	err := q.store.UpdateQueue(ctx, key, func(entries []Entry) ([]Entry, error) {
+		now := q.now()
+		entries, fnErr = fn(prepare(entries, now), now)
+		return entries, fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return errors.Wrapf(err, "failed to update queue %s", key)
+}
+
+// prepare drops terminal entries that are older than RetainTerminal and fails
+// active entries that made no progress for ActiveTimeout.
+func prepare(entries []Entry, now time.Time) []Entry {
+	retained := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if e.State.Terminal() && now.Sub(e.UpdatedAt) > RetainTerminal {
+			continue
+		}
+		if e.State.Active() && !e.isActive(now) {
+			e.Reason = fmt.Sprintf("no progress while %s for %s", e.State, ActiveTimeout)
+			e.State = StateFailed
+			e.UpdatedAt = now
+		}
+		retained = append(retained, e)
+	}
+	return retained
+}
+
+func indexOf(entries []Entry, number int) int {
+	for i, e := range entries {
+		if e.Number == number {
+			return i
+		}
+	}
+	return -1
+}
+
+func position(entries []Entry, number int) int {
+	pos := 0
+	for _, e := range entries {
+		if e.State.Terminal() {
+			continue
+		}
+		pos++
+		if e.Number == number {
+			return pos
+		}
+	}
+	return 0
+}
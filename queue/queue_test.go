@@ -0,0 +1,205 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+	key := Key{Owner: "owner", Repo: "repo", Branch: "develop"}
+
+	t.Run("enqueueKeepsPosition", func(t *testing.T) {
+		q := New(NewMemoryStore())
+
+		_, pos, err := q.Enqueue(ctx, key, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, pos)
+
+		_, pos, err = q.Enqueue(ctx, key, 5)
+		require.NoError(t, err)
+		assert.Equal(t, 2, pos)
+
+		_, pos, err = q.Enqueue(ctx, key, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, pos)
+	})
+
+	t.Run("queuesAreIndependent", func(t *testing.T) {
+		q := New(NewMemoryStore())
+		other := Key{Owner: "owner", Repo: "repo", Branch: "master"}
+
+		_, _, err := q.Enqueue(ctx, key, 1)
+		require.NoError(t, err)
+		_, pos, err := q.Enqueue(ctx, other, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 1, pos)
+	})
+
+	t.Run("nextWaitsForActive", func(t *testing.T) {
+		q := New(NewMemoryStore())
+		_, _, err := q.Enqueue(ctx, key, 1)
+		require.NoError(t, err)
+		_, _, err = q.Enqueue(ctx, key, 2)
+		require.NoError(t, err)
+
+		next, ok, err := q.Next(ctx, key)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, 1, next.Number)
+
+		_, err = q.Transition(ctx, key, 1, StateUpdating, "")
+		require.NoError(t, err)
+
+		_, ok, err = q.Next(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		_, err = q.Transition(ctx, key, 1, StateFailed, "rebase failed")
+		require.NoError(t, err)
+
+		next, ok, err = q.Next(ctx, key)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, 2, next.Number)
+
+		pos, err := q.Position(ctx, key, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 1, pos)
+	})
+
+	t.Run("invalidTransition", func(t *testing.T) {
+		q := New(NewMemoryStore())
+		_, _, err := q.Enqueue(ctx, key, 1)
+		require.NoError(t, err)
+
+		_, err = q.Transition(ctx, key, 1, StateDone, "merged")
+		require.NoError(t, err)
+
+		_, err = q.Transition(ctx, key, 1, StateMerging, "")
+		assert.Equal(t, ErrInvalidTransition, errors.Cause(err))
+
+		_, err = q.Transition(ctx, key, 2, StateMerging, "")
+		assert.Equal(t, ErrNotQueued, err)
+	})
+
+	t.Run("transitionFromRequiresState", func(t *testing.T) {
+		q := New(NewMemoryStore())
+		_, _, err := q.Enqueue(ctx, key, 1)
+		require.NoError(t, err)
+		_, err = q.Transition(ctx, key, 1, StateFailed, "cancelled")
+		require.NoError(t, err)
+
+		entry, err := q.TransitionFrom(ctx, key, 1, StateUpdating, StateQueued, "update was cancelled")
+		assert.Equal(t, ErrInvalidTransition, errors.Cause(err))
+		assert.Equal(t, StateFailed, entry.State)
+
+		_, err = q.Transition(ctx, key, 1, StateQueued, "")
+		require.NoError(t, err)
+		_, err = q.Transition(ctx, key, 1, StateUpdating, "")
+		require.NoError(t, err)
+
+		entry, err = q.TransitionFrom(ctx, key, 1, StateUpdating, StateQueued, "update was cancelled")
+		require.NoError(t, err)
+		assert.Equal(t, StateQueued, entry.State)
+	})
+
+	t.Run("terminalEntryIsRequeuedAtEnd", func(t *testing.T) {
+		q := New(NewMemoryStore())
+		_, _, err := q.Enqueue(ctx, key, 1)
+		require.NoError(t, err)
+		_, _, err = q.Enqueue(ctx, key, 2)
+		require.NoError(t, err)
+		_, err = q.Transition(ctx, key, 1, StateFailed, "")
+		require.NoError(t, err)
+
+		entry, pos, err := q.Enqueue(ctx, key, 1)
+		require.NoError(t, err)
+		assert.Equal(t, StateQueued, entry.State)
+		assert.Equal(t, 2, pos)
+	})
+
+	t.Run("staleActiveEntryIsFailed", func(t *testing.T) {
+		now := time.Now()
+		q := New(NewMemoryStore())
+		q.now = func() time.Time { return now }
+
+		_, _, err := q.Enqueue(ctx, key, 1)
+		require.NoError(t, err)
+		_, _, err = q.Enqueue(ctx, key, 2)
+		require.NoError(t, err)
+		_, err = q.Transition(ctx, key, 1, StateWaitingForCI, "")
+		require.NoError(t, err)
+
+		now = now.Add(ActiveTimeout + time.Minute)
+		next, ok, err := q.Next(ctx, key)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, 2, next.Number)
+
+		entry, _, err := q.Get(ctx, key, 1)
+		require.NoError(t, err)
+		assert.Equal(t, StateFailed, entry.State)
+	})
+	t.Run("staleActiveEntryIsExpiredOnLoad", func(t *testing.T) {
+		now := time.Now()
+		q := New(NewMemoryStore())
+		q.now = func() time.Time { return now }
+
+		_, _, err := q.Enqueue(ctx, key, 1)
+		require.NoError(t, err)
+		_, err = q.Transition(ctx, key, 1, StateUpdating, "")
+		require.NoError(t, err)
+
+		now = now.Add(ActiveTimeout + time.Minute)
+		_, ok, err := q.Active(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		_, err = q.Transition(ctx, key, 1, StateWaitingForCI, "")
+		assert.Equal(t, ErrInvalidTransition, errors.Cause(err))
+
+		entry, _, err := q.Get(ctx, key, 1)
+		require.NoError(t, err)
+		assert.Equal(t, StateFailed, entry.State)
+	})
+
+	t.Run("instancesSharingStoreKeepAllChanges", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 20; i++ {
+			wg.Add(1)
+			go func(number int) {
+				defer wg.Done()
+				_, _, err := New(store).Enqueue(ctx, key, number)
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		entries, err := New(store).Entries(ctx, key)
+		require.NoError(t, err)
+		assert.Len(t, entries, 20)
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"time"
+)
+
+// Backoff computes the delay before each attempt of a task. The delay starts
+// at Initial and grows by Multiplier up to Max.
+type Backoff struct {
+	Initial    time.Duration `yaml:"initial"`
+	Max        time.Duration `yaml:"max"`
+	Multiplier float64       `yaml:"multiplier"`
+
+	// Jitter is the fraction of each delay that is randomized, between 0
+	// and 1. It spreads out the attempts of tasks that were scheduled
+	// together.
+	Jitter float64 `yaml:"jitter"`
+}
+
+// Delay returns the delay before the attempt with the given 0-based index.
+// random is a number in [0, 1) that selects the jitter.
+func (b Backoff) Delay(attempt int, random float64) time.Duration {
+	delay := float64(b.Initial)
+	for i := 0; i < attempt && delay < float64(b.Max); i++ {
+		delay *= b.Multiplier
+	}
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	// the jittered delay is uniformly distributed in
+	// [delay * (1 - jitter), delay]
+	delay -= delay * b.Jitter * random
+	return time.Duration(delay)
+}
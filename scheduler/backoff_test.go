@@ -0,0 +1,35 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.5}
+
+	assert.Equal(t, time.Second, b.Delay(0, 0))
+	assert.Equal(t, 2*time.Second, b.Delay(1, 0))
+	assert.Equal(t, 8*time.Second, b.Delay(3, 0))
+	assert.Equal(t, 10*time.Second, b.Delay(4, 0), "delay must be capped at max")
+	assert.Equal(t, 10*time.Second, b.Delay(100, 0))
+
+	assert.Equal(t, 4*time.Second, b.Delay(2, 0))
+	assert.Equal(t, 1500*time.Millisecond, b.Delay(1, 0.5), "jitter must shorten the delay")
+}
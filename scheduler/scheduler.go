@@ -0,0 +1,381 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Key identifies the pull request of a task.
+type Key struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s/%s#%d", k.Owner, k.Repo, k.Number)
+}
+
+// Kind is the operation a task performs. A pull request has at most one task
+// of each kind.
+type Kind string
+
+const (
+	KindMerge  Kind = "merge"
+	KindUpdate Kind = "update"
+	// KindAdvance advances the queue of the pull request after its update
+	// was deferred
+	KindAdvance Kind = "advance"
+)
+
+var (
+	// ErrCancelled ends a task that was cancelled or replaced by a newer
+	// task of the same kind
+	ErrCancelled = errors.New("task was cancelled")
+	// ErrDeadlineExceeded ends a task whose next attempt would start after
+	// its deadline
+	ErrDeadlineExceeded = errors.New("task deadline exceeded")
+	// ErrAttemptsExhausted ends a task that failed MaxAttempts times
+	ErrAttemptsExhausted = errors.New("task attempts exhausted")
+)
+
+// Task is one attempt to complete a merge or an update. attempt starts at 1.
+// The task is done if it returns nil. It is attempted again if it returns an
+// error created by Retry or a transient GitHub error, and fails otherwise.
+type Task func(ctx context.Context, attempt int) error
+
+type retryError struct {
+	cause error
+}
+
+func (e *retryError) Error() string {
+	return e.cause.Error()
+}
+
+func (e *retryError) Cause() error {
+	return e.cause
+}
+
+// Retry marks err as a reason to attempt the task again.
+func Retry(err error) error {
+	return &retryError{cause: err}
+}
+
+// IsRetryable returns true if a task that returned err should be attempted
+// again.
+func IsRetryable(err error) bool {
+	for err != nil {
+		if _, ok := err.(*retryError); ok {
+			return true
+		}
+		if gerr, ok := err.(*github.ErrorResponse); ok {
+			return IsTransient(gerr)
+		}
+
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
+
+// IsTransient returns true if GitHub failed a request because of a problem
+// on its side that is likely to go away.
+func IsTransient(gerr *github.ErrorResponse) bool {
+	return gerr.Response != nil && gerr.Response.StatusCode >= http.StatusInternalServerError
+}
+
+// Config configures how tasks are retried.
+type Config struct {
+	Backoff Backoff `yaml:"backoff"`
+
+	// Deadline bounds the time between scheduling a task and its last
+	// attempt.
+	Deadline time.Duration `yaml:"deadline"`
+
+	// MaxAttempts bounds the attempts of a task. If zero,
+	// DefaultConfig.MaxAttempts applies.
+	MaxAttempts int `yaml:"max_attempts"`
+}
+
+// DefaultConfig waits for 4 seconds before the first attempt, which gives
+// GitHub time to determine if a pull request is mergeable, and retries for
+// up to 5 minutes.
+var DefaultConfig = Config{
+	Backoff: Backoff{
+		Initial:    4 * time.Second,
+		Max:        time.Minute,
+		Multiplier: 2,
+		Jitter:     0.2,
+	},
+	Deadline:    5 * time.Minute,
+	MaxAttempts: 10,
+}
+
+func (c Config) withDefaults() Config {
+	if c.Backoff.Initial <= 0 {
+		c.Backoff.Initial = DefaultConfig.Backoff.Initial
+	}
+	if c.Backoff.Max <= 0 {
+		c.Backoff.Max = DefaultConfig.Backoff.Max
+	}
+	if c.Backoff.Multiplier < 1 {
+		c.Backoff.Multiplier = DefaultConfig.Backoff.Multiplier
+	}
+	if c.Backoff.Jitter < 0 || c.Backoff.Jitter > 1 {
+		c.Backoff.Jitter = DefaultConfig.Backoff.Jitter
+	}
+	if c.Deadline <= 0 {
+		c.Deadline = DefaultConfig.Deadline
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultConfig.MaxAttempts
+	}
+	return c
+}
+
+// Job describes a pending task.
+type Job struct {
+	Key  Key  `json:"key"`
+	Kind Kind `json:"kind"`
+
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Deadline    time.Time `json:"deadline"`
+
+	// Attempts is the number of completed attempts
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+type jobKey struct {
+	key  Key
+	kind Kind
+}
+
+type job struct {
+	Job
+	cancel context.CancelFunc
+}
+
+// Scheduler runs merge and update tasks in the background, retrying them
+// with backoff until they complete, fail, exceed their deadline or are
+// cancelled.
+//
+// Tasks only live in the memory of the process. Stop cancels them when the
+// process shuts down, and their onDone callbacks record the cancellation, so
+// that the work is picked up again from the queue rather than lost.
+type Scheduler struct {
+	config Config
+	now    func() time.Time
+	random func() float64
+
+	mu      sync.Mutex
+	jobs    map[jobKey]*job
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func New(config Config) *Scheduler {
+	return &Scheduler{
+		config: config.withDefaults(),
+		now:    func() time.Time { return time.Now().UTC() },
+		random: rand.Float64,
+		jobs:   make(map[jobKey]*job),
+	}
+}
+
+// Schedule runs task for the pull request key until it completes and then
+// calls onDone with nil, or with the error that ended the task. A pending
+// task of the same kind for the same pull request is cancelled. After Stop,
+// onDone is called with ErrCancelled right away.
+//
+// The task runs with a background context that carries the logger of ctx.
+func (s *Scheduler) Schedule(ctx context.Context, key Key, kind Kind, task Task, onDone func(error)) {
+	runCtx, cancel := context.WithCancel(zerolog.Ctx(ctx).WithContext(context.Background()))
+
+	now := s.now()
+	j := &job{
+		Job: Job{
+			Key:         key,
+			Kind:        kind,
+			ScheduledAt: now,
+			Deadline:    now.Add(s.config.Deadline),
+		},
+		cancel: cancel,
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		cancel()
+		if onDone != nil {
+			onDone(ErrCancelled)
+		}
+		return
+	}
+	if previous, ok := s.jobs[jobKey{key, kind}]; ok {
+		previous.cancel()
+	}
+	s.jobs[jobKey{key, kind}] = j
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		err := s.run(runCtx, j, task)
+
+		s.mu.Lock()
+		if s.jobs[jobKey{key, kind}] == j {
+			delete(s.jobs, jobKey{key, kind})
+		}
+		s.mu.Unlock()
+
+		if onDone != nil {
+			onDone(err)
+		}
+	}()
+}
+
+func (s *Scheduler) run(ctx context.Context, j *job, task Task) error {
+	logger := zerolog.Ctx(ctx)
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		delay := s.config.Backoff.Delay(attempt, s.random())
+		next := s.now().Add(delay)
+		if next.After(j.Deadline) {
+			logger.Info().Msgf("Giving up %s of %s after %d attempts: %v", j.Kind, j.Key, attempt, lastErr)
+			return ErrDeadlineExceeded
+		}
+		s.update(j, func(j *Job) { j.NextAttempt = next })
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ErrCancelled
+		case <-timer.C:
+		}
+
+		err := task(ctx, attempt+1)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ErrCancelled
+		}
+		lastErr = err
+
+		s.update(j, func(j *Job) {
+			j.Attempts = attempt + 1
+			j.LastError = err.Error()
+		})
+		if !IsRetryable(err) {
+			return err
+		}
+		if attempt+1 >= s.config.MaxAttempts {
+			logger.Info().Msgf("Giving up %s of %s after %d attempts: %s", j.Kind, j.Key, attempt+1, err)
+			return ErrAttemptsExhausted
+		}
+		logger.Debug().Msgf("Attempt %d of %s of %s failed, retrying: %s", attempt+1, j.Kind, j.Key, err)
+	}
+}
+
+func (s *Scheduler) update(j *job, fn func(*Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&j.Job)
+}
+
+// Cancel cancels the pending task of a kind for a pull request. It returns
+// false if there is no such task.
+func (s *Scheduler) Cancel(key Key, kind Kind) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[jobKey{key, kind}]
+	if ok {
+		j.cancel()
+		delete(s.jobs, jobKey{key, kind})
+	}
+	return ok
+}
+
+// CancelAll cancels all pending tasks of a pull request.
+func (s *Scheduler) CancelAll(key Key) {
+	s.Cancel(key, KindMerge)
+	s.Cancel(key, KindUpdate)
+	s.Cancel(key, KindAdvance)
+}
+
+// Jobs returns the pending tasks, ordered by pull request and kind.
+func (s *Scheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.Job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Key != jobs[j].Key {
+			return jobs[i].Key.String() < jobs[j].Key.String()
+		}
+		return jobs[i].Kind < jobs[j].Kind
+	})
+	return jobs
+}
+
+// JobsFor returns the pending tasks of a pull request, ordered by kind.
+func (s *Scheduler) JobsFor(key Key) []Job {
+	var jobs []Job
+	for _, j := range s.Jobs() {
+		if j.Key == key {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+// Stop cancels all pending tasks and waits until their onDone callbacks
+// returned. Tasks scheduled afterwards are cancelled right away.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	for k, j := range s.jobs {
+		j.cancel()
+		delete(s.jobs, k)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
@@ -0,0 +1,174 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func errorResponse(status int, message string) *github.ErrorResponse {
+	request, _ := http.NewRequest("GET", "https://api.github.com/repos/owner/repo/pulls/1", nil)
+	return &github.ErrorResponse{Response: &http.Response{StatusCode: status, Request: request}, Message: message}
+}
+
+func newTestScheduler(maxAttempts int, deadline time.Duration) *Scheduler {
+	return New(Config{
+		Backoff:     Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2},
+		Deadline:    deadline,
+		MaxAttempts: maxAttempts,
+	})
+}
+
+func waitDone(t *testing.T, done <-chan error) error {
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "task did not finish")
+		return nil
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+	key := Key{Owner: "owner", Repo: "repo", Number: 1}
+	serverError := errorResponse(http.StatusBadGateway, "bad gateway")
+
+	t.Run("retriesUntilDone", func(t *testing.T) {
+		s := newTestScheduler(10, time.Minute)
+		done := make(chan error, 1)
+
+		var attempts []int
+		s.Schedule(ctx, key, KindMerge, func(ctx context.Context, attempt int) error {
+			attempts = append(attempts, attempt)
+			switch attempt {
+			case 1:
+				return Retry(errors.New("mergeability not known"))
+			case 2:
+				return errors.Wrap(serverError, "failed to get pull request")
+			}
+			return nil
+		}, func(err error) { done <- err })
+
+		assert.NoError(t, waitDone(t, done))
+		assert.Equal(t, []int{1, 2, 3}, attempts)
+		assert.Empty(t, s.Jobs())
+	})
+
+	t.Run("permanentErrorEndsTask", func(t *testing.T) {
+		s := newTestScheduler(10, time.Minute)
+		done := make(chan error, 1)
+
+		notFound := errorResponse(http.StatusNotFound, "not found")
+		s.Schedule(ctx, key, KindMerge, func(ctx context.Context, attempt int) error {
+			return notFound
+		}, func(err error) { done <- err })
+
+		assert.Equal(t, notFound, waitDone(t, done))
+	})
+
+	t.Run("attemptsAreBounded", func(t *testing.T) {
+		s := newTestScheduler(3, time.Minute)
+		done := make(chan error, 1)
+
+		count := 0
+		s.Schedule(ctx, key, KindUpdate, func(ctx context.Context, attempt int) error {
+			count++
+			return Retry(errors.New("not yet"))
+		}, func(err error) { done <- err })
+
+		assert.Equal(t, ErrAttemptsExhausted, waitDone(t, done))
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("deadlineIsRespected", func(t *testing.T) {
+		s := newTestScheduler(0, time.Hour)
+		now := time.Now()
+		s.now = func() time.Time { return now }
+		done := make(chan error, 1)
+
+		count := 0
+		s.Schedule(ctx, key, KindUpdate, func(ctx context.Context, attempt int) error {
+			count++
+			now = now.Add(30 * time.Minute)
+			return Retry(errors.New("not yet"))
+		}, func(err error) { done <- err })
+
+		assert.Equal(t, ErrDeadlineExceeded, waitDone(t, done))
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("pendingTasksAreInspectableAndCancellable", func(t *testing.T) {
+		s := New(Config{Backoff: Backoff{Initial: time.Hour}})
+		merged := make(chan error, 1)
+		updated := make(chan error, 1)
+
+		s.Schedule(ctx, key, KindMerge, func(ctx context.Context, attempt int) error { return nil }, func(err error) { merged <- err })
+		s.Schedule(ctx, key, KindUpdate, func(ctx context.Context, attempt int) error { return nil }, func(err error) { updated <- err })
+
+		jobs := s.JobsFor(key)
+		require.Len(t, jobs, 2)
+		assert.Equal(t, KindMerge, jobs[0].Kind)
+		assert.Equal(t, KindUpdate, jobs[1].Kind)
+		assert.Empty(t, s.JobsFor(Key{Owner: "owner", Repo: "repo", Number: 2}))
+
+		assert.True(t, s.Cancel(key, KindMerge))
+		assert.Equal(t, ErrCancelled, waitDone(t, merged))
+		assert.False(t, s.Cancel(key, KindMerge))
+
+		s.Stop()
+		assert.Equal(t, ErrCancelled, waitDone(t, updated))
+		assert.Empty(t, s.Jobs())
+	})
+
+	t.Run("newTaskReplacesPendingTask", func(t *testing.T) {
+		s := New(Config{Backoff: Backoff{Initial: time.Hour}})
+		first := make(chan error, 1)
+
+		s.Schedule(ctx, key, KindMerge, func(ctx context.Context, attempt int) error { return nil }, func(err error) { first <- err })
+		s.Schedule(ctx, key, KindMerge, func(ctx context.Context, attempt int) error { return nil }, nil)
+
+		assert.Equal(t, ErrCancelled, waitDone(t, first))
+		assert.Len(t, s.Jobs(), 1)
+		s.Stop()
+	})
+
+	t.Run("taskAfterStopIsCancelled", func(t *testing.T) {
+		s := New(Config{Backoff: Backoff{Initial: time.Hour}})
+		s.Stop()
+
+		done := make(chan error, 1)
+		s.Schedule(ctx, key, KindUpdate, func(ctx context.Context, attempt int) error {
+			require.Fail(t, "task must not run after Stop")
+			return nil
+		}, func(err error) { done <- err })
+
+		assert.Equal(t, ErrCancelled, waitDone(t, done))
+		assert.Empty(t, s.Jobs())
+	})
+
+	t.Run("attemptsDefaultToConfigDefault", func(t *testing.T) {
+		s := New(Config{})
+		assert.Equal(t, DefaultConfig.MaxAttempts, s.config.MaxAttempts)
+	})
+}
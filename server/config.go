@@ -20,6 +20,8 @@ import (
 	"github.com/palantir/go-githubapp/githubapp"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
+
+	"github.com/CyberhavenInc/bulldozer/scheduler"
 )
 
 const (
@@ -44,6 +46,27 @@ type Options struct {
 	AppName              string   `yaml:"app_name"`
 	ConfigurationPath    string   `yaml:"configuration_path"`
 	ConfigurationV0Paths []string `yaml:"configuration_v0_paths"`
+
+	// StatePath is the file where bulldozer persists its merge queues and
+	// rebase failures. If empty, state is kept in memory and lost on restart.
+	StatePath string `yaml:"state_path"`
+
+	// LockBackend selects how instances coordinate rebases and queue
+	// updates: "memory" (the default) only coordinates within one instance,
+	// "github" coordinates all instances through lock refs in each
+	// repository.
+	LockBackend string `yaml:"lock_backend"`
+
+	// StatusContext is the name of the commit status that explains why
+	// bulldozer is or is not merging a pull request. Defaults to "bulldozer".
+	StatusContext string `yaml:"status_context"`
+
+	// DisableStatus stops bulldozer from posting its commit status.
+	DisableStatus bool `yaml:"disable_status"`
+
+	// Scheduler configures how merges and updates are retried while GitHub
+	// determines mergeability or returns transient errors.
+	Scheduler scheduler.Config `yaml:"scheduler"`
 }
 
 func (o *Options) fillDefaults() {
@@ -16,7 +16,10 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/palantir/go-githubapp/githubapp"
@@ -24,14 +27,29 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/CyberhavenInc/bulldozer/bulldozer"
+	"github.com/CyberhavenInc/bulldozer/lock"
 	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/queue"
+	"github.com/CyberhavenInc/bulldozer/scheduler"
+	"github.com/CyberhavenInc/bulldozer/state"
 )
 
 type Base struct {
 	githubapp.ClientCreator
 	bulldozer.ConfigFetcher
+
+	StateStore state.StateStore
+	Queue      *queue.Queue
+	Lockers    lock.Provider
+	Scheduler  *scheduler.Scheduler
+
+	StatusReporter bulldozer.StatusReporter
 }
 
+// QueueLockTimeout bounds how long an instance may hold the lock of a branch
+// while it picks the next pull request of the queue.
+const QueueLockTimeout = time.Minute
+
 type pullWithConfig struct {
 	pr         *github.PullRequest
 	pullCtx    pull.Context
@@ -51,56 +69,270 @@ func (b *Base) ProcessPullRequest(ctx context.Context, pullCtx pull.Context, cli
 		logger.Debug().Msgf("No bulldozer configuration for %q", bulldozerConfig.String())
 	case bulldozerConfig.Invalid():
 		logger.Debug().Msgf("Bulldozer configuration is invalid for %q", bulldozerConfig.String())
+		b.reportStatus(ctx, client, pr, bulldozer.StatusError, "configuration is invalid")
 	default:
 		logger.Debug().Msgf("Bulldozer configuration is valid for %q", bulldozerConfig.String())
 		config := *bulldozerConfig.Config
-		shouldMerge, err := bulldozer.ShouldMergePR(ctx, pullCtx, config.Merge)
+		result, err := bulldozer.ShouldMergePR(ctx, pullCtx, config.Merge)
 		if err != nil {
+			b.reportStatus(ctx, client, pr, bulldozer.StatusError, result.Summary())
 			return errors.Wrap(err, "unable to determine merge status")
 		}
-		if shouldMerge {
+		if result.Allowed {
 			logger.Debug().Msg("Pull request should be merged")
-			if err := bulldozer.MergePR(ctx, pullCtx, client, config.Merge); err != nil {
+			if result.Command != nil && result.Command.Method != "" {
+				// the method of a merge command applies to all branches
+				config.Merge.Method = result.Command.Method
+				config.Merge.BranchMethod = nil
+			}
+			b.reportStatus(ctx, client, pr, bulldozer.StatusPending, "merging")
+			b.transitionQueued(ctx, pr, queue.StateMerging, "")
+			if err := bulldozer.MergePR(ctx, pullCtx, client, b.Scheduler, config.Merge, result.HeadSHA, b.onMergeResult(ctx, client, pr)); err != nil {
 				return errors.Wrap(err, "failed to merge pull request")
 			}
+		} else if len(result.FailedStatuses) > 0 {
+			b.reportStatus(ctx, client, pr, bulldozer.StatusFailure, result.Summary())
+			if b.transitionQueued(ctx, pr, queue.StateFailed, result.Reason) {
+				b.updateNext(ctx, client, pr)
+			}
+		} else if len(result.MissingStatuses) > 0 {
+			b.reportStatus(ctx, client, pr, bulldozer.StatusPending, b.describeWaiting(ctx, pr, result))
+		} else {
+			// the pull request is refused for a reason other than its status
+			// checks, so it must not keep the head of the queue while it waits
+			// for a review or a change of its signals
+			if b.releaseWaiting(ctx, pr, result.Reason) {
+				b.updateNext(ctx, client, pr)
+			}
+			b.reportStatus(ctx, client, pr, bulldozer.StatusPending, b.describeWaiting(ctx, pr, result))
 		}
 	}
 
 	return nil
 }
 
-func (b *Base) UpdatePullRequest(ctx context.Context, pullCtx pull.Context, client *github.Client, pr *github.PullRequest, baseRef string) error {
+// StatusChange is a change of a status check of a commit, reported either as
+// a commit status or as a check run.
+type StatusChange struct {
+	Name string
+
+	// State is the commit status state "pending", "success", "failure" or
+	// "error". It is ignored if CheckRun is set.
+	State string
+
+	// CheckRun is set if the change was reported through the Checks API.
+	// Whether a completed check run succeeded depends on the check
+	// conclusions configured for the pull request.
+	CheckRun *pull.CheckRun
+}
+
+func (c StatusChange) pending() bool {
+	if c.CheckRun != nil {
+		return c.CheckRun.Status != "completed"
+	}
+	return c.State == pull.StatusPending
+}
+
+func (c StatusChange) state(mergeConfig bulldozer.MergeConfig) string {
+	if c.CheckRun != nil {
+		return bulldozer.CheckRunState(*c.CheckRun, mergeConfig.CheckConclusions)
+	}
+	return c.State
+}
+
+// ProcessStatusChange reacts to changes of the status checks of commit sha.
+// Pull requests with that head commit fail in the merge queue if a status
+// check they require failed, and are evaluated again otherwise.
+func (b *Base) ProcessStatusChange(ctx context.Context, client *github.Client, owner, repoName, sha string, changes ...StatusChange) error {
+	logger := zerolog.Ctx(ctx)
+
+	var completed []StatusChange
+	for _, c := range changes {
+		switch {
+		case c.pending():
+			logger.Debug().Msgf("Doing nothing since status check %q is pending", c.Name)
+		case c.CheckRun == nil && c.State != pull.StatusSuccess && c.State != pull.StatusFailure && c.State != pull.StatusError:
+			logger.Error().Msgf("Unexpected state for %q: %q", c.Name, c.State)
+		default:
+			completed = append(completed, c)
+		}
+	}
+	if len(completed) == 0 {
+		return nil
+	}
+
+	prs, err := pull.ListOpenPullRequestsForSHA(ctx, client, owner, repoName, sha)
+	if err != nil {
+		return errors.Wrap(err, "failed to determine open pull requests matching the status context change")
+	}
+	if len(prs) == 0 {
+		logger.Debug().Msg("Doing nothing since status change event affects no open pull requests")
+		return nil
+	}
+
+	// Detect failure in recently rebased PR and schedule another rebase
+	var remaining []*github.PullRequest
+	for _, pr := range prs {
+		if reason, failed := b.requiredStatusFailure(ctx, client, owner, repoName, pr, completed); failed {
+			b.transitionQueued(ctx, pr, queue.StateFailed, reason)
+			continue
+		}
+		remaining = append(remaining, pr)
+	}
+	if len(remaining) < len(prs) {
+		if err := b.UpdateNextPullRequests(ctx, client, owner, repoName); err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msg("Failed to update another pull request")
+		}
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	// PR became outdated while building, reschedule update again
+	stillBehindBase := b.FilterUpdatablePRs(ctx, client, remaining)
+	if len(stillBehindBase) > 0 {
+		for _, p := range stillBehindBase {
+			b.transitionQueued(ctx, p.pr, queue.StateQueued, "base branch changed while waiting for CI")
+		}
+		if err := b.UpdateNextPullRequests(ctx, client, owner, repoName); err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msg("Failed to update another pull request")
+		}
+		return nil
+	}
+
+	for _, pr := range remaining {
+		pullCtx := pull.NewGithubContext(client, pr, owner, repoName, pr.GetNumber())
+		logger := logger.With().Int(githubapp.LogKeyPRNum, pr.GetNumber()).Logger()
+
+		if err := b.ProcessPullRequest(logger.WithContext(ctx), pullCtx, client, pr); err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msg("Error processing pull request")
+		}
+	}
+
+	return nil
+}
+
+// requiredStatusFailure returns a description of the first change that fails
+// a status check required by a pull request and true, or false if none of the
+// changes fails a required check. The configuration of the pull request
+// determines which check run conclusions are failures and, together with
+// branch protection, which checks are required.
+func (b *Base) requiredStatusFailure(ctx context.Context, client *github.Client, owner, repoName string, pr *github.PullRequest, changes []StatusChange) (string, bool) {
 	logger := zerolog.Ctx(ctx)
 
 	bulldozerConfig, err := b.ConfigForPR(ctx, client, pr)
 	if err != nil {
-		return errors.Wrap(err, "failed to fetch configuration")
+		logger.Warn().Err(err).Msgf("Failed to fetch configuration of pull request #%d", pr.GetNumber())
+		return "", false
+	}
+	if bulldozerConfig.Missing() || bulldozerConfig.Invalid() {
+		return "", false
 	}
+	mergeConfig := bulldozerConfig.Config.Merge
 
-	switch {
-	case bulldozerConfig.Missing():
-		logger.Debug().Msgf("No bulldozer configuration for %q", bulldozerConfig.String())
-	case bulldozerConfig.Invalid():
-		logger.Debug().Msgf("Bulldozer configuration is invalid for %q", bulldozerConfig.String())
-	default:
-		logger.Debug().Msgf("Bulldozer configuration is valid for %q", bulldozerConfig.String())
-		config := *bulldozerConfig.Config
+	pullCtx := pull.NewGithubContext(client, pr, owner, repoName, pr.GetNumber())
+	var required []string
+	for _, c := range changes {
+		state := c.state(mergeConfig)
+		if state != pull.StatusFailure && state != pull.StatusError {
+			continue
+		}
 
-		shouldUpdate, err := bulldozer.ShouldUpdatePR(ctx, pullCtx, config.Update)
+		if required == nil {
+			if required, err = bulldozer.RequiredStatusChecks(ctx, pullCtx, mergeConfig); err != nil {
+				logger.Warn().Err(err).Msgf("Failed to determine required status checks of pull request #%d", pr.GetNumber())
+				return "", false
+			}
+		}
+		for _, name := range required {
+			if name == c.Name {
+				return fmt.Sprintf("status %q is %s", c.Name, state), true
+			}
+		}
+	}
+	return "", false
+}
 
-		if err != nil {
-			return errors.Wrap(err, "unable to determine update status")
+// describeWaiting returns the commit status description for a pull request
+// that is not merged yet, including its progress through the merge queue.
+func (b *Base) describeWaiting(ctx context.Context, pr *github.PullRequest, result bulldozer.EvaluationResult) string {
+	key := queueKey(pr)
+
+	entry, ok, err := b.Queue.Get(ctx, key, pr.GetNumber())
+	if err != nil || !ok {
+		return result.Summary()
+	}
+
+	switch entry.State {
+	case queue.StateQueued:
+		pos, err := b.Queue.Position(ctx, key, pr.GetNumber())
+		if err == nil && pos > 0 {
+			return fmt.Sprintf("queued, position %d", pos)
 		}
+	case queue.StateUpdating:
+		return "updating branch"
+	}
+	return result.Summary()
+}
+
+// reportStatus publishes the bulldozer commit status for the head commit of a
+// pull request. Failures are logged since the status is informational.
+func (b *Base) reportStatus(ctx context.Context, client *github.Client, pr *github.PullRequest, state, description string) {
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := pr.GetBase().GetRepo().GetName()
+
+	if err := b.StatusReporter.Report(ctx, client, owner, repo, pr.GetHead().GetSHA(), state, description); err != nil {
+		zerolog.Ctx(ctx).Error().Err(errors.WithStack(err)).Msg("Failed to report bulldozer status")
+	}
+}
+
+// onMergeResult returns a callback that reports the result of a merge and
+// moves the pull request in the queue if the merge did not happen.
+func (b *Base) onMergeResult(ctx context.Context, client *github.Client, pr *github.PullRequest) func(bulldozer.MergeResult) {
+	logger := zerolog.Ctx(ctx)
+	ctx = logger.WithContext(context.Background())
 
-		if shouldUpdate {
-			logger.Debug().Msg("Pull request should be updated")
-			if err := bulldozer.UpdatePR(ctx, pullCtx, client, config.Update, baseRef, AddActivePR); err != nil {
-				return errors.Wrap(err, "failed to update pull request")
+	return func(result bulldozer.MergeResult) {
+		switch result.Outcome {
+		case bulldozer.MergeSucceeded:
+			b.reportStatus(ctx, client, pr, bulldozer.StatusSuccess, "merged")
+		case bulldozer.MergeSkipped:
+			b.reportStatus(ctx, client, pr, bulldozer.StatusPending, "merge skipped: "+result.Reason)
+			b.transitionQueued(ctx, pr, queue.StateWaitingForCI, result.Reason)
+		case bulldozer.MergeHeadModified:
+			// the push that modified the head triggers a new evaluation
+			b.reportStatus(ctx, client, pr, bulldozer.StatusPending, "merge postponed: "+result.Reason)
+			b.transitionQueued(ctx, pr, queue.StateWaitingForCI, result.Reason)
+		default:
+			state := bulldozer.StatusFailure
+			if result.Outcome == bulldozer.MergeFailed {
+				state = bulldozer.StatusError
+			}
+			b.reportStatus(ctx, client, pr, state, fmt.Sprintf("merge %s: %s", result.Outcome, result.Reason))
+			b.recordMergeError(ctx, pr, result)
+
+			if b.transitionQueued(ctx, pr, queue.StateFailed, fmt.Sprintf("merge %s: %s", result.Outcome, result.Reason)) {
+				key := queueKey(pr)
+				if err := b.UpdateNextPullRequests(ctx, client, key.Owner, key.Repo); err != nil {
+					logger.Error().Err(errors.WithStack(err)).Msg("Failed to update another pull request")
+				}
 			}
 		}
 	}
+}
+
+// recordMergeError stores a failed merge so it can be explained later.
+// Failures are logged since the record is informational.
+func (b *Base) recordMergeError(ctx context.Context, pr *github.PullRequest, result bulldozer.MergeResult) {
+	message := fmt.Sprintf("%s: %s", result.Outcome, result.Reason)
+	if result.Err != nil {
+		message = fmt.Sprintf("%s: %v", message, result.Err)
+	}
 
-	return nil
+	key := state.PRKey{Owner: pr.GetBase().GetRepo().GetOwner().GetLogin(), Repo: pr.GetBase().GetRepo().GetName(), Number: pr.GetNumber()}
+	if err := b.StateStore.SetLastError(ctx, key, state.PRError{Operation: "merge", Message: message, At: time.Now().UTC()}); err != nil {
+		zerolog.Ctx(ctx).Error().Err(errors.WithStack(err)).Msg("Failed to record merge error")
+	}
 }
 
 func (b *Base) FilterUpdatablePRs(ctx context.Context, client *github.Client, prs []*github.PullRequest) (result []pullWithConfig) {
@@ -121,19 +353,33 @@ func (b *Base) FilterUpdatablePRs(ctx context.Context, client *github.Client, pr
 		config := *bulldozerConfig.Config
 		pullCtx := pull.NewGithubContext(client, pr, bulldozerConfig.Owner, bulldozerConfig.Repo, pr.GetNumber())
 
-		canUpdate, err := bulldozer.ShouldUpdatePR(ctx, pullCtx, config.Update)
+		evaluation, err := bulldozer.ShouldUpdatePR(ctx, pullCtx, config.Update)
 		if err != nil {
 			logger.Debug().Msgf("unable to determine whitelist status: %v", err)
 			continue
 		}
 
+		if !evaluation.Allowed {
+			continue
+		}
+
+		failed, err := bulldozer.FailedStatusChecks(ctx, pullCtx, config.Merge)
+		if err != nil {
+			logger.Debug().Msgf("unable to determine status checks: %v", err)
+			continue
+		}
+		if len(failed) > 0 {
+			logger.Debug().Msgf("Not updating %q since required status checks failed: [%s]", pullCtx.Locator(), strings.Join(failed, ","))
+			continue
+		}
+
 		behindBase, err := bulldozer.IsPRBehindBase(ctx, client, pullCtx)
 		if err != nil {
 			logger.Debug().Msgf("unable to determine update status: %v", err)
 			continue
 		}
 
-		if canUpdate && behindBase {
+		if behindBase {
 			result = append(result, pullWithConfig{pr: pr, pullCtx: pullCtx, pullConfig: config})
 		}
 	}
@@ -141,13 +387,13 @@ func (b *Base) FilterUpdatablePRs(ctx context.Context, client *github.Client, pr
 	return
 }
 
-func (b *Base) UpdateOldestPullRequest(ctx context.Context, client *github.Client, prs []pullWithConfig) error {
-	if len(prs) == 0 {
-		return nil
-	}
+// EnqueuePullRequests adds pull requests that should be updated to the queue
+// of their base branch, oldest first, and starts updating the head of each
+// affected queue unless another pull request in that queue is active.
+func (b *Base) EnqueuePullRequests(ctx context.Context, client *github.Client, prs []pullWithConfig) error {
+	logger := zerolog.Ctx(ctx)
 
-	// Other PRs are being updated
-	if ActivePRPresent() {
+	if len(prs) == 0 {
 		return nil
 	}
 
@@ -156,11 +402,224 @@ func (b *Base) UpdateOldestPullRequest(ctx context.Context, client *github.Clien
 		return prs[i].pr.GetCreatedAt().Before(prs[j].pr.GetCreatedAt())
 	})
 
-	oldest := prs[0]
-	baseRef := oldest.pr.GetBase().GetRef()
-	if err := bulldozer.UpdatePR(ctx, oldest.pullCtx, client, oldest.pullConfig.Update, baseRef, AddActivePR); err != nil {
-		return errors.Wrap(err, "failed to update pull request")
+	var keys []queue.Key
+	candidates := make(map[queue.Key]map[int]pullWithConfig)
+	for _, p := range prs {
+		key := queueKey(p.pr)
+		if _, ok := candidates[key]; !ok {
+			keys = append(keys, key)
+			candidates[key] = make(map[int]pullWithConfig)
+		}
+		candidates[key][p.pr.GetNumber()] = p
+
+		_, position, err := b.Queue.Enqueue(ctx, key, p.pr.GetNumber())
+		if err != nil {
+			return errors.Wrapf(err, "failed to enqueue %q", p.pullCtx.Locator())
+		}
+		logger.Debug().Msgf("%q is at position %d of queue %s", p.pullCtx.Locator(), position, key)
+	}
+
+	for _, key := range keys {
+		if err := b.advanceQueue(ctx, client, key, candidates[key]); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// UpdateNextPullRequests enqueues all open pull requests of a repository that
+// should be updated and advances the affected queues.
+func (b *Base) UpdateNextPullRequests(ctx context.Context, client *github.Client, owner, repoName string) error {
+	prs, err := pull.ListOpenPullRequests(ctx, client, owner, repoName)
+	if err != nil {
+		return err
+	}
+
+	return b.EnqueuePullRequests(ctx, client, b.FilterUpdatablePRs(ctx, client, prs))
+}
+
+// advanceQueue starts updating the first queued pull request if no pull
+// request in the queue is active. Queued pull requests that no longer need an
+// update are removed.
+func (b *Base) advanceQueue(ctx context.Context, client *github.Client, key queue.Key, candidates map[int]pullWithConfig) error {
+	p, ok, err := b.claimNext(ctx, client, key, candidates)
+	if err != nil || !ok {
+		return err
+	}
+
+	if err := bulldozer.UpdatePR(ctx, p.pullCtx, client, b.Scheduler, b.StateStore, b.Lockers(client), p.pullConfig.Update, key.Branch, b.onUpdateResult(ctx, client, key, p.pr.GetNumber())); err != nil {
+		return errors.Wrap(err, "failed to update pull request")
+	}
+	return nil
+}
+
+// claimNext moves the first queued pull request that still needs an update to
+// the updating state and returns it. It holds the queue lock of the base
+// branch while doing so, so that only one instance starts work on a queue. The
+// queue lock is separate from the rebase lock of the branch, so the queue
+// advances while a rebase is running.
+func (b *Base) claimNext(ctx context.Context, client *github.Client, key queue.Key, candidates map[int]pullWithConfig) (pullWithConfig, bool, error) {
+	logger := zerolog.Ctx(ctx)
+
+	locker := b.Lockers(client)
+	lockKey := lock.Key{Owner: key.Owner, Repo: key.Repo, Branch: key.Branch, Scope: lock.ScopeQueue}
+	lease, acquired, err := locker.Acquire(ctx, lockKey, "queue", QueueLockTimeout)
+	if err != nil {
+		return pullWithConfig{}, false, errors.Wrapf(err, "failed to lock queue %s", key)
+	}
+	if !acquired {
+		logger.Debug().Msgf("Not advancing queue %s, lock is held by %s since %s", key, lease.Holder, lease.AcquiredAt.Format(time.RFC3339))
+		return pullWithConfig{}, false, nil
+	}
+	defer func() {
+		if err := locker.Release(ctx, lease); err != nil {
+			logger.Error().Err(err).Msgf("Failed to release lock of queue %s", key)
+		}
+	}()
+
+	for {
+		next, ok, err := b.Queue.Next(ctx, key)
+		if err != nil {
+			return pullWithConfig{}, false, errors.Wrapf(err, "failed to determine next pull request of queue %s", key)
+		}
+		if !ok {
+			logger.Debug().Msgf("Queue %s has an active pull request or is empty", key)
+			return pullWithConfig{}, false, nil
+		}
+
+		p, ok := candidates[next.Number]
+		if !ok {
+			pr, _, err := client.PullRequests.Get(ctx, key.Owner, key.Repo, next.Number)
+			if err != nil {
+				return pullWithConfig{}, false, errors.Wrapf(err, "failed to get pull request %s/%s#%d", key.Owner, key.Repo, next.Number)
+			}
+
+			filtered := b.FilterUpdatablePRs(ctx, client, []*github.PullRequest{pr})
+			if len(filtered) == 0 {
+				logger.Debug().Msgf("Removing %s/%s#%d from queue %s since it no longer needs an update", key.Owner, key.Repo, next.Number, key)
+				if _, err := b.Queue.Remove(ctx, key, next.Number); err != nil {
+					return pullWithConfig{}, false, err
+				}
+				continue
+			}
+			p = filtered[0]
+		}
+
+		if _, err := b.Queue.Transition(ctx, key, next.Number, queue.StateUpdating, ""); err != nil {
+			return pullWithConfig{}, false, err
+		}
+		return p, true, nil
+	}
+}
+
+// onUpdateResult returns a callback that moves a pull request in the queue
+// according to the result of its update. Results only apply while the pull
+// request is updating, so that they do not undo a cancellation. A cancelled
+// update returns the pull request to the queue, where the next instance to
+// advance the queue picks it up. A deferred update also returns it to the
+// queue, which is then advanced again by the scheduler.
+func (b *Base) onUpdateResult(ctx context.Context, client *github.Client, key queue.Key, number int) func(bulldozer.UpdateResult) {
+	logger := zerolog.Ctx(ctx)
+	ctx = logger.WithContext(context.Background())
+
+	return func(result bulldozer.UpdateResult) {
+		var state queue.State
+		switch result.Outcome {
+		case bulldozer.UpdateSucceeded, bulldozer.UpdateNotNeeded:
+			state = queue.StateWaitingForCI
+		case bulldozer.UpdateDeferred, bulldozer.UpdateCancelled:
+			state = queue.StateQueued
+		default:
+			state = queue.StateFailed
+		}
+
+		reason := result.Reason
+		if result.Err != nil {
+			reason = fmt.Sprintf("%s: %v", reason, result.Err)
+		}
+
+		if _, err := b.Queue.TransitionFrom(ctx, key, number, queue.StateUpdating, state, reason); err != nil {
+			if errors.Cause(err) == queue.ErrInvalidTransition || err == queue.ErrNotQueued {
+				logger.Debug().Msgf("Not moving pull request #%d of queue %s to %s: %v", number, key, state, err)
+			} else {
+				logger.Error().Err(err).Msgf("Failed to move pull request #%d of queue %s to %s", number, key, state)
+			}
+			return
+		}
+
+		if result.Outcome == bulldozer.UpdateDeferred {
+			b.scheduleAdvance(ctx, client, key, number)
+		}
+	}
+}
+
+// scheduleAdvance advances a queue again on behalf of a pull request whose
+// update was deferred, after the initial backoff of the scheduler.
+func (b *Base) scheduleAdvance(ctx context.Context, client *github.Client, key queue.Key, number int) {
+	logger := zerolog.Ctx(ctx)
+
+	schedKey := scheduler.Key{Owner: key.Owner, Repo: key.Repo, Number: number}
+	b.Scheduler.Schedule(ctx, schedKey, scheduler.KindAdvance, func(ctx context.Context, attempt int) error {
+		return b.advanceQueue(ctx, client, key, nil)
+	}, func(err error) {
+		if err != nil && err != scheduler.ErrCancelled {
+			logger.Error().Err(errors.WithStack(err)).Msgf("Failed to advance queue %s", key)
+		}
+	})
+}
+
+// transitionQueued moves a pull request to a new state if it has an entry in
+// the queue of its base branch that is not in a terminal state. It returns
+// true if the pull request was active before the transition.
+func (b *Base) transitionQueued(ctx context.Context, pr *github.PullRequest, state queue.State, reason string) bool {
+	logger := zerolog.Ctx(ctx)
+	key := queueKey(pr)
+
+	entry, ok, err := b.Queue.Get(ctx, key, pr.GetNumber())
+	if err != nil {
+		logger.Error().Err(err).Msgf("Failed to look up pull request #%d in queue %s", pr.GetNumber(), key)
+		return false
+	}
+	if !ok || entry.State.Terminal() {
+		return false
+	}
+
+	if _, err := b.Queue.Transition(ctx, key, pr.GetNumber(), state, reason); err != nil {
+		logger.Debug().Msgf("Not moving pull request #%d of queue %s: %v", pr.GetNumber(), key, err)
+	}
+	return entry.State.Active()
+}
+
+// releaseWaiting moves a pull request that holds the head of its queue while
+// waiting for CI to the failed state, so that the queue can advance. It
+// returns true if the pull request was moved.
+func (b *Base) releaseWaiting(ctx context.Context, pr *github.PullRequest, reason string) bool {
+	logger := zerolog.Ctx(ctx)
+	key := queueKey(pr)
+
+	if _, err := b.Queue.TransitionFrom(ctx, key, pr.GetNumber(), queue.StateWaitingForCI, queue.StateFailed, reason); err != nil {
+		if errors.Cause(err) != queue.ErrInvalidTransition && err != queue.ErrNotQueued {
+			logger.Error().Err(err).Msgf("Failed to release pull request #%d of queue %s", pr.GetNumber(), key)
+		}
+		return false
+	}
+	return true
+}
+
+// updateNext advances the queues of the repository of a pull request after
+// the pull request left the head of its queue. Failures are logged.
+func (b *Base) updateNext(ctx context.Context, client *github.Client, pr *github.PullRequest) {
+	key := queueKey(pr)
+	if err := b.UpdateNextPullRequests(ctx, client, key.Owner, key.Repo); err != nil {
+		zerolog.Ctx(ctx).Error().Err(errors.WithStack(err)).Msg("Failed to update another pull request")
+	}
+}
+
+func queueKey(pr *github.PullRequest) queue.Key {
+	return queue.Key{
+		Owner:  pr.GetBase().GetRepo().GetOwner().GetLogin(),
+		Repo:   pr.GetBase().GetRepo().GetName(),
+		Branch: pr.GetBase().GetRef(),
+	}
+}
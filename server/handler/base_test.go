@@ -0,0 +1,185 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/bulldozer"
+	"github.com/CyberhavenInc/bulldozer/lock"
+	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/pull/pulltest"
+	"github.com/CyberhavenInc/bulldozer/queue"
+	"github.com/CyberhavenInc/bulldozer/scheduler"
+	"github.com/CyberhavenInc/bulldozer/state"
+)
+
+const testConfig = `
+version: 1
+merge:
+  whitelist:
+    labels: ["merge when ready"]
+  method: squash
+update:
+  whitelist:
+    labels: ["update me"]
+`
+
+// fakeGitHub implements the parts of the GitHub API used to find and
+// evaluate the pull requests that need an update. All pull requests are
+// behind their base branch and have no status checks.
+type fakeGitHub struct {
+	prs []*github.PullRequest
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/repos/owner/repo/")
+
+	switch {
+	case strings.HasPrefix(path, "contents/"):
+		writeJSON(w, http.StatusOK, github.RepositoryContent{
+			Type:     github.String("file"),
+			Encoding: github.String("base64"),
+			Content:  github.String(base64.StdEncoding.EncodeToString([]byte(testConfig))),
+		})
+
+	case path == "pulls":
+		writeJSON(w, http.StatusOK, f.prs)
+
+	case strings.HasPrefix(path, "pulls/"):
+		for _, pr := range f.prs {
+			if path == fmt.Sprintf("pulls/%d", pr.GetNumber()) {
+				writeJSON(w, http.StatusOK, pr)
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+
+	case strings.HasSuffix(path, "/status"):
+		writeJSON(w, http.StatusOK, github.CombinedStatus{})
+
+	case strings.HasSuffix(path, "/check-runs"):
+		writeJSON(w, http.StatusOK, github.ListCheckRunsResults{Total: github.Int(0)})
+
+	case strings.HasPrefix(path, "compare/"):
+		writeJSON(w, http.StatusOK, github.CommitsComparison{BehindBy: github.Int(1)})
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestPR(number int, labels ...string) *github.PullRequest {
+	createdAt := time.Date(2018, 6, 1, 12, number, 0, 0, time.UTC)
+	pr := &github.PullRequest{
+		Number:    github.Int(number),
+		State:     github.String("open"),
+		Mergeable: github.Bool(true),
+		CreatedAt: &createdAt,
+		Base: &github.PullRequestBranch{
+			Ref: github.String("develop"),
+			Repo: &github.Repository{
+				Name:  github.String("repo"),
+				Owner: &github.User{Login: github.String("owner")},
+			},
+		},
+		Head: &github.PullRequestBranch{
+			SHA:  github.String(fmt.Sprintf("head%d", number)),
+			Repo: &github.Repository{Fork: github.Bool(false)},
+		},
+	}
+	for _, label := range labels {
+		pr.Labels = append(pr.Labels, &github.Label{Name: github.String(label)})
+	}
+	return pr
+}
+
+func TestProcessPullRequestReleasesRefusedPullRequest(t *testing.T) {
+	ctx := context.Background()
+
+	first := newTestPR(1, "merge when ready")
+	second := newTestPR(2, "update me")
+
+	srv := httptest.NewServer(&fakeGitHub{prs: []*github.PullRequest{first, second}})
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	sched := scheduler.New(scheduler.Config{
+		Backoff:  scheduler.Backoff{Initial: time.Hour},
+		Deadline: 2 * time.Hour,
+	})
+	defer sched.Stop()
+
+	b := &Base{
+		ConfigFetcher:  bulldozer.NewConfigFetcher(".bulldozer.v1.yml", nil),
+		StateStore:     state.NewMemoryStore(),
+		Queue:          queue.New(queue.NewMemoryStore()),
+		Lockers:        lock.MemoryProvider(lock.NewManager()),
+		Scheduler:      sched,
+		StatusReporter: bulldozer.StatusReporter{Disabled: true},
+	}
+
+	key := queueKey(first)
+	for _, number := range []int{1, 2} {
+		_, _, err := b.Queue.Enqueue(ctx, key, number)
+		require.NoError(t, err)
+	}
+	_, err := b.Queue.Transition(ctx, key, 1, queue.StateUpdating, "")
+	require.NoError(t, err)
+	_, err = b.Queue.Transition(ctx, key, 1, queue.StateWaitingForCI, "")
+	require.NoError(t, err)
+
+	// CI of the updated pull request passed, but it is not approved
+	pullCtx := &pulltest.MockPullContext{
+		OwnerValue:              "owner",
+		RepoValue:               "repo",
+		NumberValue:             1,
+		LabelValue:              []string{"merge when ready"},
+		ReviewRequirementsValue: pull.ReviewRequirements{RequiredApprovals: 1},
+	}
+	require.NoError(t, b.ProcessPullRequest(ctx, pullCtx, client, first))
+
+	entry, ok, err := b.Queue.Get(ctx, key, 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, queue.StateFailed, entry.State)
+	assert.Contains(t, entry.Reason, "unsatisfied reviews")
+
+	entry, ok, err = b.Queue.Get(ctx, key, 2)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, queue.StateUpdating, entry.State, "the next pull request must be updated")
+}
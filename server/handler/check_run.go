@@ -0,0 +1,68 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/go-github/github"
+	"github.com/palantir/go-githubapp/githubapp"
+	"github.com/pkg/errors"
+
+	"github.com/CyberhavenInc/bulldozer/pull"
+)
+
+type CheckRun struct {
+	Base
+}
+
+func (h *CheckRun) Handles() []string {
+	return []string{"check_run"}
+}
+
+func (h *CheckRun) Handle(ctx context.Context, eventType, deliveryID string, payload []byte) error {
+	var event github.CheckRunEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return errors.Wrap(err, "failed to parse check run event payload")
+	}
+
+	repo := event.GetRepo()
+	run := event.GetCheckRun()
+	installationID := githubapp.GetInstallationIDFromEvent(&event)
+	ctx, _ = githubapp.PrepareRepoContext(ctx, installationID, repo)
+
+	client, err := h.ClientCreator.NewInstallationClient(installationID)
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate github client")
+	}
+
+	return h.ProcessStatusChange(ctx, client, repo.GetOwner().GetLogin(), repo.GetName(), run.GetHeadSHA(), checkRunChange(run))
+}
+
+// checkRunChange describes the change of a check run. Whether the run
+// succeeded is determined with the configuration of each pull request.
+func checkRunChange(run *github.CheckRun) StatusChange {
+	return StatusChange{
+		Name: run.GetName(),
+		CheckRun: &pull.CheckRun{
+			Name:       run.GetName(),
+			Status:     run.GetStatus(),
+			Conclusion: run.GetConclusion(),
+		},
+	}
+}
+
+var _ githubapp.EventHandler = &CheckRun{}
@@ -0,0 +1,85 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/go-github/github"
+	"github.com/palantir/go-githubapp/githubapp"
+	"github.com/pkg/errors"
+)
+
+type CheckSuite struct {
+	Base
+}
+
+func (h *CheckSuite) Handles() []string {
+	return []string{"check_suite"}
+}
+
+// Handle re-evaluates pull requests when all check runs of a suite completed.
+// A suite has no name of its own, so failures are attributed to the app that
+// owns the suite; failures of required checks are handled by CheckRun.
+func (h *CheckSuite) Handle(ctx context.Context, eventType, deliveryID string, payload []byte) error {
+	var event github.CheckSuiteEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return errors.Wrap(err, "failed to parse check suite event payload")
+	}
+
+	repo := event.GetRepo()
+	suite := event.GetCheckSuite()
+	installationID := githubapp.GetInstallationIDFromEvent(&event)
+	ctx, logger := githubapp.PrepareRepoContext(ctx, installationID, repo)
+
+	if event.GetAction() != "completed" {
+		logger.Debug().Msgf("Doing nothing since check suite action was %q", event.GetAction())
+		return nil
+	}
+
+	client, err := h.ClientCreator.NewInstallationClient(installationID)
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate github client")
+	}
+
+	// the suite is not a status check itself, so report the check runs it
+	// contains, which are what branch protection and the configuration
+	// require
+	owner, repoName := repo.GetOwner().GetLogin(), repo.GetName()
+	opts := &github.ListCheckRunsOptions{
+		Filter:      github.String("latest"),
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var changes []StatusChange
+	for {
+		result, res, err := client.Checks.ListCheckRunsCheckSuite(ctx, owner, repoName, suite.GetID(), opts)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list check runs of check suite %d", suite.GetID())
+		}
+		for _, run := range result.CheckRuns {
+			changes = append(changes, checkRunChange(run))
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return h.ProcessStatusChange(ctx, client, owner, repoName, suite.GetHeadSHA(), changes...)
+}
+
+var _ githubapp.EventHandler = &CheckSuite{}
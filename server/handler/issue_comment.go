@@ -22,6 +22,7 @@ import (
 	"github.com/palantir/go-githubapp/githubapp"
 	"github.com/pkg/errors"
 
+	"github.com/CyberhavenInc/bulldozer/bulldozer"
 	"github.com/CyberhavenInc/bulldozer/pull"
 )
 
@@ -46,6 +47,11 @@ func (h *IssueComment) Handle(ctx context.Context, eventType, deliveryID string,
 	installationID := githubapp.GetInstallationIDFromEvent(&event)
 	ctx, logger := githubapp.PreparePRContext(ctx, installationID, repo, number)
 
+	if !event.GetIssue().IsPullRequest() {
+		logger.Debug().Msg("Doing nothing since comment is not on a pull request")
+		return nil
+	}
+
 	client, err := h.ClientCreator.NewInstallationClient(installationID)
 	if err != nil {
 		return errors.Wrap(err, "failed to instantiate github client")
@@ -57,6 +63,13 @@ func (h *IssueComment) Handle(ctx context.Context, eventType, deliveryID string,
 	}
 	pullCtx := pull.NewGithubContext(client, pr, owner, repoName, number)
 
+	if command, ok := bulldozer.ParseSlashCommand(event.GetComment().GetBody()); ok && event.GetAction() == "created" {
+		if err := h.ProcessSlashCommand(ctx, pullCtx, client, pr, event.GetComment(), command); err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msgf("Error processing %q", command.String())
+		}
+		return nil
+	}
+
 	if err := h.ProcessPullRequest(ctx, pullCtx, client, pr); err != nil {
 		logger.Error().Err(errors.WithStack(err)).Msg("Error processing pull request")
 	}
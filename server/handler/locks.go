@@ -0,0 +1,39 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"net/http"
+
+	"github.com/palantir/go-baseapp/baseapp"
+
+	"github.com/CyberhavenInc/bulldozer/lock"
+)
+
+type LockList struct {
+	Locks []lock.Lease `json:"locks"`
+}
+
+// Locks lists the locks currently held by this instance and who holds them.
+func Locks(locks *lock.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leases, err := locks.Leases(r.Context())
+		if err != nil {
+			baseapp.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		baseapp.WriteJSON(w, http.StatusOK, &LockList{Locks: leases})
+	})
+}
@@ -17,14 +17,15 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	"github.com/google/go-github/github"
 	"github.com/palantir/go-githubapp/githubapp"
 	"github.com/pkg/errors"
 
-	"github.com/CyberhavenInc/bulldozer/bulldozer"
 	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/queue"
+	"github.com/CyberhavenInc/bulldozer/scheduler"
+	"github.com/CyberhavenInc/bulldozer/state"
 )
 
 type PullRequest struct {
@@ -49,16 +50,44 @@ func (h *PullRequest) Handle(ctx context.Context, eventType, deliveryID string,
 	ctx, logger := githubapp.PreparePRContext(ctx, installationID, repo, number)
 	action := event.GetAction()
 
+	client, err := h.ClientCreator.NewInstallationClient(installationID)
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate github client")
+	}
+
 	if action == "closed" {
 		logger.Debug().Msg("Doing nothing since pull request is closed")
-		bulldozer.RemoveFailedPR(number)
-		RmoveActivePR(fmt.Sprintf("%s/%s#%d", owner, repoName, number))
+		prKey := state.PRKey{Owner: owner, Repo: repoName, Number: number}
+		if err := h.StateStore.ClearFailedRebase(ctx, prKey); err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msg("Failed to clear rebase failures")
+		}
+		if err := h.StateStore.ClearLastError(ctx, prKey); err != nil {
+			logger.Error().Err(errors.WithStack(err)).Msg("Failed to clear last error")
+		}
+		h.Scheduler.CancelAll(scheduler.Key{Owner: owner, Repo: repoName, Number: number})
+
+		if event.GetPullRequest().GetMerged() {
+			h.transitionQueued(ctx, event.GetPullRequest(), queue.StateDone, "merged")
+			return nil
+		}
+
+		// A merge triggers a push that advances the queue, other closes don't
+		if h.transitionQueued(ctx, event.GetPullRequest(), queue.StateFailed, "closed without merging") {
+			if err := h.UpdateNextPullRequests(ctx, client, owner, repoName); err != nil {
+				logger.Error().Err(errors.WithStack(err)).Msg("Error updating queued pull requests")
+			}
+		}
 		return nil
 	}
 
-	client, err := h.ClientCreator.NewInstallationClient(installationID)
-	if err != nil {
-		return errors.Wrap(err, "failed to instantiate github client")
+	// A draft is not merged or updated, so it gives up its place in the queue
+	if action == "converted_to_draft" {
+		h.Scheduler.CancelAll(scheduler.Key{Owner: owner, Repo: repoName, Number: number})
+		if h.transitionQueued(ctx, event.GetPullRequest(), queue.StateFailed, "converted to draft") {
+			if err := h.UpdateNextPullRequests(ctx, client, owner, repoName); err != nil {
+				logger.Error().Err(errors.WithStack(err)).Msg("Error updating queued pull requests")
+			}
+		}
 	}
 
 	pr, _, err := client.PullRequests.Get(ctx, owner, repoName, number)
@@ -67,9 +96,11 @@ func (h *PullRequest) Handle(ctx context.Context, eventType, deliveryID string,
 	}
 	pullCtx := pull.NewGithubContext(client, pr, owner, repoName, number)
 
-	// Try to update this PR if no other PR is currently being updated
-	if (action == "labeled" || action == "unlabeled") && !ActivePRPresent() {
-		if err := h.UpdatePullRequest(ctx, pullCtx, client, pr, pr.GetBase().GetRef()); err != nil {
+	// Queue this PR for updates, it is updated immediately if no other PR is
+	// currently being updated
+	if action == "labeled" || action == "unlabeled" || action == "ready_for_review" {
+		filtered := h.FilterUpdatablePRs(ctx, client, []*github.PullRequest{pr})
+		if err := h.EnqueuePullRequests(ctx, client, filtered); err != nil {
 			logger.Error().Err(errors.WithStack(err)).Msg("Error updating pull request")
 		}
 	}
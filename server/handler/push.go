@@ -73,8 +73,8 @@ func (h *Push) Handle(ctx context.Context, eventType, deliveryID string, payload
 		return nil
 	}
 
-	if err := h.UpdateOldestPullRequest(ctx, client, filtered); err != nil {
-		logger.Error().Err(errors.WithStack(err)).Msg("Error updating oldest pull request")
+	if err := h.EnqueuePullRequests(ctx, client, filtered); err != nil {
+		logger.Error().Err(errors.WithStack(err)).Msg("Error updating queued pull requests")
 	}
 
 	return nil
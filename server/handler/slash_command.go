@@ -0,0 +1,293 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/CyberhavenInc/bulldozer/bulldozer"
+	"github.com/CyberhavenInc/bulldozer/pull"
+	"github.com/CyberhavenInc/bulldozer/queue"
+	"github.com/CyberhavenInc/bulldozer/scheduler"
+	"github.com/CyberhavenInc/bulldozer/state"
+)
+
+// Reactions used to acknowledge slash commands
+const (
+	reactionAccepted = "+1"
+	reactionRejected = "confused"
+)
+
+// ProcessSlashCommand acts on a slash command posted in comment and
+// acknowledges it with a reaction. Problems with the command are explained in
+// a reply.
+func (b *Base) ProcessSlashCommand(ctx context.Context, pullCtx pull.Context, client *github.Client, pr *github.PullRequest, comment *github.IssueComment, command bulldozer.SlashCommand) error {
+	logger := zerolog.Ctx(ctx)
+	author := comment.GetUser().GetLogin()
+
+	if err := command.Validate(); err != nil {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		b.reply(ctx, client, pullCtx, fmt.Sprintf("@%s %s. %s", author, err, slashCommandUsage))
+		return nil
+	}
+
+	permission, err := pullCtx.Permission(ctx, author)
+	if err != nil {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		return errors.Wrapf(err, "failed to determine permission of %s", author)
+	}
+	if permission != "admin" && permission != "write" {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		b.reply(ctx, client, pullCtx, fmt.Sprintf("@%s `%s` requires write permission on this repository.", author, command))
+		return nil
+	}
+
+	if command.Name == bulldozer.SlashMerge || command.Name == bulldozer.SlashCancel || command.Name == bulldozer.SlashUpdate {
+		config, ok, err := b.validConfig(ctx, client, pr)
+		if err != nil {
+			b.react(ctx, client, pullCtx, comment, reactionRejected)
+			return err
+		}
+		if !ok || !config.Merge.Commands.SlashCommands {
+			b.react(ctx, client, pullCtx, comment, reactionRejected)
+			b.reply(ctx, client, pullCtx, fmt.Sprintf("@%s `%s` is not enabled by the bulldozer configuration of this repository.", author, command))
+			return nil
+		}
+	}
+
+	logger.Info().Msgf("Processing %q of %s", command.String(), author)
+
+	switch command.Name {
+	case bulldozer.SlashMerge:
+		// the command is picked up from the comments when evaluating the
+		// pull request
+		b.react(ctx, client, pullCtx, comment, reactionAccepted)
+		return b.ProcessPullRequest(ctx, pullCtx, client, pr)
+
+	case bulldozer.SlashCancel:
+		b.react(ctx, client, pullCtx, comment, reactionAccepted)
+		b.Scheduler.CancelAll(scheduler.Key{Owner: pullCtx.Owner(), Repo: pullCtx.Repo(), Number: pullCtx.Number()})
+		if b.transitionQueued(ctx, pr, queue.StateFailed, "cancelled by "+author) {
+			if err := b.UpdateNextPullRequests(ctx, client, pullCtx.Owner(), pullCtx.Repo()); err != nil {
+				logger.Error().Err(errors.WithStack(err)).Msg("Error updating queued pull requests")
+			}
+		}
+		return b.ProcessPullRequest(ctx, pullCtx, client, pr)
+
+	case bulldozer.SlashUpdate:
+		return b.slashUpdate(ctx, pullCtx, client, pr, comment)
+
+	case bulldozer.SlashStatus:
+		return b.slashStatus(ctx, pullCtx, client, pr, comment)
+
+	case bulldozer.SlashQueue:
+		return b.slashQueue(ctx, pullCtx, client, pr, comment)
+
+	case bulldozer.SlashExplain:
+		return b.slashExplain(ctx, pullCtx, client, pr, comment)
+	}
+	return nil
+}
+
+const slashCommandUsage = "Available commands are `/bulldozer merge [squash|rebase|merge]`, " +
+	"`/bulldozer update`, `/bulldozer status`, `/bulldozer queue`, `/bulldozer explain`, and `/bulldozer cancel`."
+
+// slashUpdate queues the pull request for an update right away if the update
+// configuration selects it.
+func (b *Base) slashUpdate(ctx context.Context, pullCtx pull.Context, client *github.Client, pr *github.PullRequest, comment *github.IssueComment) error {
+	config, ok, err := b.validConfig(ctx, client, pr)
+	if err != nil || !ok {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		if err == nil {
+			b.reply(ctx, client, pullCtx, "The bulldozer configuration of this repository is missing or invalid.")
+		}
+		return err
+	}
+
+	result, err := bulldozer.ShouldUpdatePR(ctx, pullCtx, config.Update)
+	if err != nil {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		return errors.Wrap(err, "failed to determine if pull request should be updated")
+	}
+	if !result.Allowed {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		b.reply(ctx, client, pullCtx, fmt.Sprintf("@%s This pull request is not selected by the update configuration: %s", comment.GetUser().GetLogin(), result.Reason))
+		return nil
+	}
+
+	behind, err := bulldozer.IsPRBehindBase(ctx, client, pullCtx)
+	if err != nil {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		return errors.Wrap(err, "failed to determine if pull request is behind its base")
+	}
+
+	b.react(ctx, client, pullCtx, comment, reactionAccepted)
+	if !behind {
+		b.reply(ctx, client, pullCtx, "This pull request is already up to date with its base branch.")
+		return nil
+	}
+
+	return b.EnqueuePullRequests(ctx, client, []pullWithConfig{{pr: pr, pullCtx: pullCtx, pullConfig: config}})
+}
+
+// slashStatus replies with the merge evaluation of the pull request.
+func (b *Base) slashStatus(ctx context.Context, pullCtx pull.Context, client *github.Client, pr *github.PullRequest, comment *github.IssueComment) error {
+	config, ok, err := b.validConfig(ctx, client, pr)
+	if err != nil || !ok {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		if err == nil {
+			b.reply(ctx, client, pullCtx, "The bulldozer configuration of this repository is missing or invalid.")
+		}
+		return err
+	}
+
+	result, err := bulldozer.ShouldMergePR(ctx, pullCtx, config.Merge)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(errors.WithStack(err)).Msg("Failed to evaluate pull request")
+	}
+
+	b.react(ctx, client, pullCtx, comment, reactionAccepted)
+	b.reply(ctx, client, pullCtx, fmt.Sprintf("**Status:** %s\n\n%s", b.describeWaiting(ctx, pr, result), result.Reason))
+	return nil
+}
+
+// slashQueue replies with the queue of the base branch of the pull request.
+func (b *Base) slashQueue(ctx context.Context, pullCtx pull.Context, client *github.Client, pr *github.PullRequest, comment *github.IssueComment) error {
+	key := queueKey(pr)
+	entries, err := b.Queue.Entries(ctx, key)
+	if err != nil {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		return errors.Wrapf(err, "failed to list queue %s", key)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "**Queue of `%s`**\n\n", key.Branch)
+
+	listed := 0
+	for _, entry := range entries {
+		if entry.State.Terminal() {
+			continue
+		}
+		listed++
+		fmt.Fprintf(&body, "%d. #%d %s", listed, entry.Number, entry.State)
+		if entry.Reason != "" {
+			fmt.Fprintf(&body, " (%s)", entry.Reason)
+		}
+		body.WriteString("\n")
+	}
+	if listed == 0 {
+		body.WriteString("The queue is empty.\n")
+	}
+
+	b.react(ctx, client, pullCtx, comment, reactionAccepted)
+	b.reply(ctx, client, pullCtx, body.String())
+	return nil
+}
+
+// slashExplain replies with a report of the merge and update evaluations,
+// the queue, and the last error of the pull request.
+func (b *Base) slashExplain(ctx context.Context, pullCtx pull.Context, client *github.Client, pr *github.PullRequest, comment *github.IssueComment) error {
+	logger := zerolog.Ctx(ctx)
+
+	fetched, err := b.ConfigForPR(ctx, client, pr)
+	if err != nil {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		return errors.Wrap(err, "failed to fetch configuration")
+	}
+	if fetched.Missing() || fetched.Invalid() {
+		b.react(ctx, client, pullCtx, comment, reactionRejected)
+		reason := "is missing"
+		if fetched.Invalid() {
+			reason = fmt.Sprintf("is invalid: %s", fetched.Error)
+		}
+		b.reply(ctx, client, pullCtx, fmt.Sprintf("The bulldozer configuration of `%s` %s.", fetched.String(), reason))
+		return nil
+	}
+	config := *fetched.Config
+
+	explanation := bulldozer.Explanation{Config: fetched.String()}
+
+	if explanation.Merge, err = bulldozer.ShouldMergePR(ctx, pullCtx, config.Merge); err != nil {
+		logger.Error().Err(errors.WithStack(err)).Msg("Failed to evaluate merge of pull request")
+	}
+	if explanation.Update, err = bulldozer.ShouldUpdatePR(ctx, pullCtx, config.Update); err != nil {
+		logger.Error().Err(errors.WithStack(err)).Msg("Failed to evaluate update of pull request")
+	}
+	if behind, err := bulldozer.IsPRBehindBase(ctx, client, pullCtx); err != nil {
+		logger.Error().Err(errors.WithStack(err)).Msg("Failed to determine if pull request is behind its base")
+	} else {
+		explanation.BehindBase = &behind
+	}
+
+	key := queueKey(pr)
+	if entry, ok, err := b.Queue.Get(ctx, key, pr.GetNumber()); err != nil {
+		logger.Error().Err(errors.WithStack(err)).Msgf("Failed to look up queue %s", key)
+	} else if ok {
+		explanation.QueueEntry = &entry
+		if entry.State == queue.StateQueued {
+			if pos, err := b.Queue.Position(ctx, key, pr.GetNumber()); err == nil {
+				explanation.QueuePosition = pos
+			}
+		}
+	}
+
+	prKey := state.PRKey{Owner: pullCtx.Owner(), Repo: pullCtx.Repo(), Number: pullCtx.Number()}
+	if lastError, ok, err := b.StateStore.LastError(ctx, prKey); err != nil {
+		logger.Error().Err(errors.WithStack(err)).Msg("Failed to look up last error of pull request")
+	} else if ok {
+		explanation.LastError = &lastError
+	}
+
+	explanation.Attempts = b.Scheduler.JobsFor(scheduler.Key{Owner: pullCtx.Owner(), Repo: pullCtx.Repo(), Number: pullCtx.Number()})
+
+	b.react(ctx, client, pullCtx, comment, reactionAccepted)
+	b.reply(ctx, client, pullCtx, explanation.Markdown())
+	return nil
+}
+
+// validConfig returns the configuration of a pull request and true, or false
+// if the configuration is missing or invalid.
+func (b *Base) validConfig(ctx context.Context, client *github.Client, pr *github.PullRequest) (bulldozer.Config, bool, error) {
+	fetched, err := b.ConfigForPR(ctx, client, pr)
+	if err != nil {
+		return bulldozer.Config{}, false, errors.Wrap(err, "failed to fetch configuration")
+	}
+	if fetched.Missing() || fetched.Invalid() {
+		return bulldozer.Config{}, false, nil
+	}
+	return *fetched.Config, true, nil
+}
+
+// react adds a reaction to a comment. Failures are logged since reactions are
+// informational.
+func (b *Base) react(ctx context.Context, client *github.Client, pullCtx pull.Context, comment *github.IssueComment, content string) {
+	if _, _, err := client.Reactions.CreateIssueCommentReaction(ctx, pullCtx.Owner(), pullCtx.Repo(), comment.GetID(), content); err != nil {
+		zerolog.Ctx(ctx).Error().Err(errors.WithStack(err)).Msgf("Failed to react to comment %d", comment.GetID())
+	}
+}
+
+// reply comments on a pull request. Failures are logged since replies are
+// informational.
+func (b *Base) reply(ctx context.Context, client *github.Client, pullCtx pull.Context, body string) {
+	if _, _, err := client.Issues.CreateComment(ctx, pullCtx.Owner(), pullCtx.Repo(), pullCtx.Number(), &github.IssueComment{Body: github.String(body)}); err != nil {
+		zerolog.Ctx(ctx).Error().Err(errors.WithStack(err)).Msgf("Failed to comment on %s", pullCtx.Locator())
+	}
+}
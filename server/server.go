@@ -27,13 +27,18 @@ import (
 	"goji.io/pat"
 
 	"github.com/CyberhavenInc/bulldozer/bulldozer"
+	"github.com/CyberhavenInc/bulldozer/lock"
+	"github.com/CyberhavenInc/bulldozer/queue"
+	"github.com/CyberhavenInc/bulldozer/scheduler"
 	"github.com/CyberhavenInc/bulldozer/server/handler"
+	"github.com/CyberhavenInc/bulldozer/state"
 	"github.com/CyberhavenInc/bulldozer/version"
 )
 
 type Server struct {
-	config *Config
-	base   *baseapp.Server
+	config    *Config
+	base      *baseapp.Server
+	scheduler *scheduler.Scheduler
 }
 
 // New instantiates a new Server.
@@ -63,12 +68,39 @@ func New(c *Config) (*Server, error) {
 		return nil, errors.Wrap(err, "failed to initialize Github client creator")
 	}
 
+	var stateStore state.StateStore = state.NewMemoryStore()
+	if c.Options.StatePath != "" {
+		stateStore = state.NewFileStore(c.Options.StatePath)
+	}
+
+	var lockers lock.Provider
+	switch c.Options.LockBackend {
+	case "", lock.BackendMemory:
+		lockers = lock.MemoryProvider(lock.NewManager())
+	case lock.BackendGitHub:
+		lockers = lock.GitHubProvider()
+	default:
+		return nil, errors.Errorf("unknown lock backend %q", c.Options.LockBackend)
+	}
+	lockRecorder := lock.NewRecorder()
+	sched := scheduler.New(c.Options.Scheduler)
+
 	baseHandler := handler.Base{
 		ClientCreator: clientCreator,
 		ConfigFetcher: bulldozer.NewConfigFetcher(c.Options.ConfigurationPath, c.Options.ConfigurationV0Paths),
+		StateStore:    stateStore,
+		Queue:         queue.New(stateStore),
+		Lockers:       lockRecorder.Wrap(lockers),
+		Scheduler:     sched,
+		StatusReporter: bulldozer.StatusReporter{
+			Context:  c.Options.StatusContext,
+			Disabled: c.Options.DisableStatus,
+		},
 	}
 
 	webhookHandler := githubapp.NewDefaultEventDispatcher(c.Github,
+		&handler.CheckRun{Base: baseHandler},
+		&handler.CheckSuite{Base: baseHandler},
 		&handler.IssueComment{Base: baseHandler},
 		&handler.PullRequest{Base: baseHandler},
 		&handler.PullRequestReview{Base: baseHandler},
@@ -83,10 +115,13 @@ func New(c *Config) (*Server, error) {
 
 	// any additional API routes
 	mux.Handle(pat.Get("/api/health"), handler.Health())
+	mux.Handle(pat.Get("/api/locks"), handler.Locks(lockRecorder))
+	mux.Handle(pat.Get("/api/scheduler"), handler.Jobs(sched))
 
 	return &Server{
-		config: c,
-		base:   base,
+		config:    c,
+		base:      base,
+		scheduler: sched,
 	}, nil
 }
 
@@ -118,3 +153,10 @@ func (s *Server) Start() error {
 	}
 	return s.base.Start()
 }
+
+// Stop cancels the pending merges and updates and waits until the queue
+// entries of cancelled updates are back in the queue, so that another
+// instance or the next start picks them up.
+func (s *Server) Stop() {
+	s.scheduler.Stop()
+}
@@ -0,0 +1,278 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/CyberhavenInc/bulldozer/queue"
+)
+
+// FileStore is a StateStore that keeps all state in a single JSON file. The
+// file is read on every access and replaced atomically on every change.
+// Accesses hold a lock on a separate ".lock" file next to it, shared for
+// reads and exclusive for changes, so that processes on the same host or on a
+// file system with working flock(2) support can share the file without losing
+// each other's changes.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+type fileState struct {
+	Queues        []fileQueue        `json:"queues,omitempty"`
+	FailedRebases []fileFailedRebase `json:"failed_rebases,omitempty"`
+	LastErrors    []fileLastError    `json:"last_errors,omitempty"`
+}
+
+type fileQueue struct {
+	Key     queue.Key     `json:"key"`
+	Entries []queue.Entry `json:"entries"`
+}
+
+type fileFailedRebase struct {
+	PR PRKey     `json:"pr"`
+	At time.Time `json:"at"`
+}
+
+type fileLastError struct {
+	PR    PRKey   `json:"pr"`
+	Error PRError `json:"error"`
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) LoadQueue(ctx context.Context, key queue.Key) ([]queue.Entry, error) {
+	var entries []queue.Entry
+	err := s.view(func(fs *fileState) {
+		for _, q := range fs.Queues {
+			if q.Key == key {
+				entries = q.Entries
+			}
+		}
+	})
+	return entries, err
+}
+
+func (s *FileStore) UpdateQueue(ctx context.Context, key queue.Key, fn func([]queue.Entry) ([]queue.Entry, error)) error {
+	return s.update(func(fs *fileState) error {
+		var current []queue.Entry
+		queues := fs.Queues[:0]
+		for _, q := range fs.Queues {
+			if q.Key == key {
+				current = q.Entries
+			} else {
+				queues = append(queues, q)
+			}
+		}
+
+		entries, err := fn(current)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			queues = append(queues, fileQueue{Key: key, Entries: entries})
+		}
+		fs.Queues = queues
+		return nil
+	})
+}
+
+func (s *FileStore) FailedRebase(ctx context.Context, pr PRKey) (time.Time, bool, error) {
+	var at time.Time
+	var ok bool
+	err := s.view(func(fs *fileState) {
+		for _, f := range fs.FailedRebases {
+			if f.PR == pr {
+				at, ok = f.At, true
+			}
+		}
+	})
+	return at, ok, err
+}
+
+func (s *FileStore) SetFailedRebase(ctx context.Context, pr PRKey, at time.Time) error {
+	return s.update(func(fs *fileState) error {
+		fs.FailedRebases = append(withoutFailedRebase(fs.FailedRebases, pr), fileFailedRebase{PR: pr, At: at})
+		return nil
+	})
+}
+
+func (s *FileStore) ClearFailedRebase(ctx context.Context, pr PRKey) error {
+	return s.update(func(fs *fileState) error {
+		fs.FailedRebases = withoutFailedRebase(fs.FailedRebases, pr)
+		return nil
+	})
+}
+
+func withoutFailedRebase(failed []fileFailedRebase, pr PRKey) []fileFailedRebase {
+	result := failed[:0]
+	for _, f := range failed {
+		if f.PR != pr {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func (s *FileStore) LastError(ctx context.Context, pr PRKey) (PRError, bool, error) {
+	var last PRError
+	var ok bool
+	err := s.view(func(fs *fileState) {
+		for _, e := range fs.LastErrors {
+			if e.PR == pr {
+				last, ok = e.Error, true
+			}
+		}
+	})
+	return last, ok, err
+}
+
+func (s *FileStore) SetLastError(ctx context.Context, pr PRKey, err PRError) error {
+	return s.update(func(fs *fileState) error {
+		fs.LastErrors = append(withoutLastError(fs.LastErrors, pr), fileLastError{PR: pr, Error: err})
+		return nil
+	})
+}
+
+func (s *FileStore) ClearLastError(ctx context.Context, pr PRKey) error {
+	return s.update(func(fs *fileState) error {
+		fs.LastErrors = withoutLastError(fs.LastErrors, pr)
+		return nil
+	})
+}
+
+func withoutLastError(errors []fileLastError, pr PRKey) []fileLastError {
+	result := errors[:0]
+	for _, e := range errors {
+		if e.PR != pr {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (s *FileStore) view(fn func(*fileState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock(syscall.LOCK_SH)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fs, err := s.read()
+	if err != nil {
+		return err
+	}
+	fn(fs)
+	return nil
+}
+
+func (s *FileStore) update(fn func(*fileState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock(syscall.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fs, err := s.read()
+	if err != nil {
+		return err
+	}
+	if err := fn(fs); err != nil {
+		return err
+	}
+	return s.write(fs)
+}
+
+// lock takes a flock(2) lock of the given type on the lock file and returns a
+// function that releases it. The state file itself cannot be locked because
+// write replaces it.
+func (s *FileStore) lock(how int) (func(), error) {
+	path := s.path + ".lock"
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open lock file %s", path)
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "failed to lock %s", path)
+	}
+
+	return func() {
+		// closing the file releases the lock
+		f.Close()
+	}, nil
+}
+
+func (s *FileStore) read() (*fileState, error) {
+	var fs fileState
+
+	bytes, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &fs, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read state file %s", s.path)
+	}
+
+	if err := json.Unmarshal(bytes, &fs); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse state file %s", s.path)
+	}
+	return &fs, nil
+}
+
+func (s *FileStore) write(fs *fileState) error {
+	bytes, err := json.MarshalIndent(fs, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal state")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temporary file for %s", s.path)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to write %s", tmp.Name())
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "failed to write %s", tmp.Name())
+	}
+
+	return errors.Wrapf(os.Rename(tmp.Name(), s.path), "failed to replace %s", s.path)
+}
+
+// type assertion
+var _ StateStore = &FileStore{}
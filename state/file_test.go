@@ -0,0 +1,114 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CyberhavenInc/bulldozer/queue"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "bulldozer-state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state.json")
+
+	t.Run("queuesSurviveReopen", func(t *testing.T) {
+		key := queue.Key{Owner: "owner", Repo: "repo", Branch: "develop"}
+
+		_, _, err := queue.New(NewFileStore(path)).Enqueue(ctx, key, 1)
+		require.NoError(t, err)
+		_, _, err = queue.New(NewFileStore(path)).Enqueue(ctx, key, 2)
+		require.NoError(t, err)
+
+		entries, err := queue.New(NewFileStore(path)).Entries(ctx, key)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, 1, entries[0].Number)
+		assert.Equal(t, 2, entries[1].Number)
+	})
+
+	t.Run("failedRebasesAreKeyedByRepository", func(t *testing.T) {
+		store := NewFileStore(path)
+		at := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+		require.NoError(t, store.SetFailedRebase(ctx, PRKey{"owner", "repo-a", 1}, at))
+
+		_, ok, err := NewFileStore(path).FailedRebase(ctx, PRKey{"owner", "repo-b", 1})
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		actual, ok, err := NewFileStore(path).FailedRebase(ctx, PRKey{"owner", "repo-a", 1})
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, at.Equal(actual))
+
+		require.NoError(t, store.ClearFailedRebase(ctx, PRKey{"owner", "repo-a", 1}))
+		_, ok, err = NewFileStore(path).FailedRebase(ctx, PRKey{"owner", "repo-a", 1})
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("lastErrorIsReplaced", func(t *testing.T) {
+		store := NewFileStore(path)
+		pr := PRKey{"owner", "repo", 2}
+		at := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+		require.NoError(t, store.SetLastError(ctx, pr, PRError{Operation: "rebase", Message: "conflict", At: at}))
+		require.NoError(t, store.SetLastError(ctx, pr, PRError{Operation: "merge", Message: "not mergeable", At: at.Add(time.Minute)}))
+
+		actual, ok, err := NewFileStore(path).LastError(ctx, pr)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "merge", actual.Operation)
+		assert.Equal(t, "not mergeable", actual.Message)
+
+		require.NoError(t, store.ClearLastError(ctx, pr))
+		_, ok, err = NewFileStore(path).LastError(ctx, pr)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("storesSharingFileKeepAllChanges", func(t *testing.T) {
+		key := queue.Key{Owner: "owner", Repo: "repo", Branch: "release"}
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 10; i++ {
+			wg.Add(1)
+			go func(number int) {
+				defer wg.Done()
+				_, _, err := queue.New(NewFileStore(path)).Enqueue(ctx, key, number)
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		entries, err := queue.New(NewFileStore(path)).Entries(ctx, key)
+		require.NoError(t, err)
+		assert.Len(t, entries, 10)
+	})
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/CyberhavenInc/bulldozer/queue"
+)
+
+// MemoryStore is a StateStore that keeps all state in process memory. State
+// is lost when the process exits.
+type MemoryStore struct {
+	*queue.MemoryStore
+
+	mu            sync.Mutex
+	failedRebases map[PRKey]time.Time
+	lastErrors    map[PRKey]PRError
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		MemoryStore:   queue.NewMemoryStore(),
+		failedRebases: make(map[PRKey]time.Time),
+		lastErrors:    make(map[PRKey]PRError),
+	}
+}
+
+func (s *MemoryStore) FailedRebase(ctx context.Context, pr PRKey) (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	at, ok := s.failedRebases[pr]
+	return at, ok, nil
+}
+
+func (s *MemoryStore) SetFailedRebase(ctx context.Context, pr PRKey, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failedRebases[pr] = at
+	return nil
+}
+
+func (s *MemoryStore) ClearFailedRebase(ctx context.Context, pr PRKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failedRebases, pr)
+	return nil
+}
+
+func (s *MemoryStore) LastError(ctx context.Context, pr PRKey) (PRError, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err, ok := s.lastErrors[pr]
+	return err, ok, nil
+}
+
+func (s *MemoryStore) SetLastError(ctx context.Context, pr PRKey, err PRError) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastErrors[pr] = err
+	return nil
+}
+
+func (s *MemoryStore) ClearLastError(ctx context.Context, pr PRKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.lastErrors, pr)
+	return nil
+}
+
+// type assertion
+var _ StateStore = &MemoryStore{}
@@ -0,0 +1,74 @@
+// Copyright 2018 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CyberhavenInc/bulldozer/queue"
+)
+
+// PRKey identifies a pull request across all repositories of all
+// installations.
+type PRKey struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+}
+
+// String returns the key in the same format as pull.Context.Locator
+func (k PRKey) String() string {
+	return fmt.Sprintf("%s/%s#%d", k.Owner, k.Repo, k.Number)
+}
+
+// PRError is the last error of an operation on a pull request.
+type PRError struct {
+	// Operation is the failed operation, e.g. "merge" or "rebase"
+	Operation string    `json:"operation"`
+	Message   string    `json:"message"`
+	At        time.Time `json:"at"`
+}
+
+// StateStore persists the state bulldozer needs between events: the merge
+// queues, which contain the pull requests that are actively being updated,
+// the time of the last failed rebase, and the last error of each pull
+// request.
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	queue.Store
+
+	// FailedRebase returns the time of the last failed rebase of a pull
+	// request and true, or false if no failure is recorded.
+	FailedRebase(ctx context.Context, pr PRKey) (time.Time, bool, error)
+
+	// SetFailedRebase records a failed rebase of a pull request.
+	SetFailedRebase(ctx context.Context, pr PRKey, at time.Time) error
+
+	// ClearFailedRebase removes the failed rebase record of a pull request.
+	ClearFailedRebase(ctx context.Context, pr PRKey) error
+
+	// LastError returns the last error recorded for a pull request and
+	// true, or false if no error is recorded.
+	LastError(ctx context.Context, pr PRKey) (PRError, bool, error)
+
+	// SetLastError records an error of a pull request, replacing the
+	// previous one.
+	SetLastError(ctx context.Context, pr PRKey, err PRError) error
+
+	// ClearLastError removes the error record of a pull request.
+	ClearLastError(ctx context.Context, pr PRKey) error
+}